@@ -1,12 +1,19 @@
 package main_test
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -134,6 +141,1674 @@ task from stdin line 3`
 	})
 }
 
+func TestTodoCLIPriority(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "todo-test-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	t.Run("AddTasksWithPriority", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "add", "--priority", "C", "low task")
+		require.NoError(t, err)
+
+		_, err = runCommand(tmpFile.Name(), "add", "unprioritized task")
+		require.NoError(t, err)
+
+		_, err = runCommand(tmpFile.Name(), "add", "-p", "a", "urgent task")
+		require.NoError(t, err)
+	})
+
+	t.Run("RejectInvalidPriority", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "add", "--priority", "urgent", "bad task")
+		require.Error(t, err)
+	})
+
+	t.Run("PrioritizeTask", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "prioritize", "2", "B")
+		require.NoError(t, err)
+		assert.Regexp(t, `Set priority of task #2 \[[a-z]{4}\] to B\.`, output)
+	})
+
+	t.Run("ListSortedByPriority", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list", "--sort", "priority")
+		require.NoError(t, err)
+
+		assert.Equal(t, "3. [ ] (A) urgent task\n2. [ ] (B) unprioritized task\n1. [ ] (C) low task\n", withoutIDs(output))
+	})
+
+	t.Run("ListWithMinPriority", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list", "--min-priority", "B")
+		require.NoError(t, err)
+
+		assert.Contains(t, output, "urgent task")
+		assert.Contains(t, output, "unprioritized task")
+		assert.NotContains(t, output, "low task")
+	})
+
+	t.Run("ClearPriority", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "prioritize", "2", "none")
+		require.NoError(t, err)
+		assert.Regexp(t, `Cleared priority of task #2 \[[a-z]{4}\]\.`, output)
+	})
+}
+
+func TestTodoCLIDueDates(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "todo-test-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	t.Run("AddTasksWithDueDates", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "add", "--due", "2000-01-01", "file taxes")
+		require.NoError(t, err)
+
+		_, err = runCommand(tmpFile.Name(), "add", "--due", "today", "stand-up notes")
+		require.NoError(t, err)
+
+		_, err = runCommand(tmpFile.Name(), "add", "--due", "+30d", "plan offsite")
+		require.NoError(t, err)
+
+		_, err = runCommand(tmpFile.Name(), "add", "someday task")
+		require.NoError(t, err)
+	})
+
+	t.Run("RejectInvalidDueDate", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "add", "--due", "whenever", "bad task")
+		require.Error(t, err)
+	})
+
+	t.Run("ListMarksOverdue", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "1. [ ] file taxes (due 2000-01-01, OVERDUE)")
+	})
+
+	t.Run("ListOverdue", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list", "--overdue")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] file taxes (due 2000-01-01, OVERDUE)\n", withoutIDs(output))
+	})
+
+	t.Run("ListDueWithin", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list", "--due-within", "7d")
+		require.NoError(t, err)
+
+		assert.Contains(t, output, "file taxes")
+		assert.Contains(t, output, "stand-up notes")
+		assert.NotContains(t, output, "plan offsite")
+		assert.NotContains(t, output, "someday task")
+	})
+
+	t.Run("ListDueBefore", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list", "--due-before", "today")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] file taxes (due 2000-01-01, OVERDUE)\n", withoutIDs(output))
+	})
+
+	t.Run("RejectDueBeforeWithDueWithin", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "list", "--due-before", "today", "--due-within", "7d")
+		assert.Equal(t, 2, exitCode(t, err))
+	})
+
+	t.Run("Agenda", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "agenda")
+		require.NoError(t, err)
+
+		assert.Regexp(t, `(?s)Overdue:.*file taxes.*Today:.*stand-up notes.*Later:.*plan offsite.*someday task`, output)
+		assert.NotContains(t, output, "This week:")
+	})
+
+	t.Run("FlagsAfterTaskText", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "add", "ship release", "--due", "2030-05-03")
+		require.NoError(t, err)
+		assert.Contains(t, output, `Added task: "ship release"`)
+
+		output, err = runCommand(tmpFile.Name(), "list", "--due-before", "2030-05-04")
+		require.NoError(t, err)
+		assert.Contains(t, withoutIDs(output), "5. [ ] ship release (due 2030-05-03)\n")
+
+		// Words that only look like flags stay in the task text.
+		output, err = runCommand(tmpFile.Name(), "add", "call", "mom", "-urgent")
+		require.NoError(t, err)
+		assert.Contains(t, output, `Added task: "call mom -urgent"`)
+
+		// "--" ends the flags for text that starts with a dash.
+		_, err = runCommand(tmpFile.Name(), "add", "--", "--due is not a flag")
+		require.NoError(t, err)
+		output, err = runCommand(tmpFile.Name(), "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "--due is not a flag")
+	})
+
+	t.Run("NoDueDateIsOmitted", func(t *testing.T) {
+		data, err := os.ReadFile(tmpFile.Name())
+		require.NoError(t, err)
+
+		var items []map[string]any
+		require.NoError(t, json.Unmarshal(data, &items))
+		assert.Contains(t, items[0], "due")
+		assert.NotContains(t, items[3], "due")
+	})
+}
+
+func TestTodoCLITags(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "todo-test-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	t.Run("AddTaggedTasks", func(t *testing.T) {
+		for _, task := range []string{
+			"write changelog +release @office",
+			"tag the build +release",
+			"buy milk @errands",
+		} {
+			_, err := runCommand(tmpFile.Name(), "add", task)
+			require.NoError(t, err)
+		}
+
+		_, err := runCommand(tmpFile.Name(), "complete", "2")
+		require.NoError(t, err)
+	})
+
+	t.Run("ListByTagAndProject", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list", "--tag", "@office", "--project", "release")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] write changelog +release @office\n", withoutIDs(output))
+	})
+
+	t.Run("ListByProject", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list", "--project", "+release")
+		require.NoError(t, err)
+
+		assert.Contains(t, output, "write changelog")
+		assert.Contains(t, output, "tag the build")
+		assert.NotContains(t, output, "buy milk")
+	})
+
+	t.Run("AgendaByTag", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "agenda", "--tag", "@errands")
+		require.NoError(t, err)
+
+		assert.Contains(t, output, "buy milk")
+		assert.NotContains(t, output, "write changelog")
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "tags")
+		require.NoError(t, err)
+
+		assert.Regexp(t, `\+release\s+1 open\s+1 done`, output)
+		assert.Regexp(t, `@errands\s+1 open\s+0 done`, output)
+		assert.Regexp(t, `@office\s+1 open\s+0 done`, output)
+	})
+}
+
+func TestTodoCLIStableIDs(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "todo-test-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	var secondID string
+
+	t.Run("AddPrintsID", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "add", "first")
+		require.NoError(t, err)
+
+		output, err := runCommand(tmpFile.Name(), "add", "second")
+		require.NoError(t, err)
+
+		m := regexp.MustCompile(`Added task: "second" \[([a-z]{4})\]`).FindStringSubmatch(output)
+		require.NotNil(t, m, "expected ID in output: %s", output)
+		secondID = m[1]
+
+		_, err = runCommand(tmpFile.Name(), "add", "third")
+		require.NoError(t, err)
+	})
+
+	t.Run("ListShowsID", func(t *testing.T) {
+		output, err := runCommand(tmpFile.Name(), "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, fmt.Sprintf("[%s] 2. [ ] second", secondID))
+	})
+
+	t.Run("DeleteShiftsPositionsButNotIDs", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "delete", "1")
+		require.NoError(t, err)
+
+		output, err := runCommand(tmpFile.Name(), "complete", secondID)
+		require.NoError(t, err)
+		assert.Contains(t, output, fmt.Sprintf("Marked task #1 [%s] as completed.", secondID))
+
+		output, err = runCommand(tmpFile.Name(), "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [x] second\n2. [ ] third\n", withoutIDs(output))
+	})
+
+	t.Run("RejectUnknownID", func(t *testing.T) {
+		_, err := runCommand(tmpFile.Name(), "delete", "zzzz")
+		require.Error(t, err)
+	})
+}
+
+func TestTodoCLIConcurrentAdds(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	const procs = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, procs)
+
+	for n := range procs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := runCommand(todoFile, "add", fmt.Sprintf("parallel task %d", n))
+			if err != nil {
+				err = fmt.Errorf("%w: %s", err, out)
+			}
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	output, err := runCommand(todoFile, "list")
+	require.NoError(t, err)
+
+	for n := range procs {
+		assert.Contains(t, output, fmt.Sprintf("parallel task %d\n", n), "task %d was lost", n)
+	}
+}
+
+func TestTodoCLIUndoRedo(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	t.Run("Setup", func(t *testing.T) {
+		_, err := runCommand(todoFile, "add", "keep me")
+		require.NoError(t, err)
+
+		_, err = runCommand(todoFile, "add", "delete me")
+		require.NoError(t, err)
+
+		_, err = runCommand(todoFile, "delete", "2")
+		require.NoError(t, err)
+	})
+
+	t.Run("UndoDelete", func(t *testing.T) {
+		output, err := runCommand(todoFile, "undo")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Undid: delete 2")
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] keep me\n2. [ ] delete me\n", withoutIDs(output))
+	})
+
+	t.Run("History", func(t *testing.T) {
+		output, err := runCommand(todoFile, "history")
+		require.NoError(t, err)
+
+		assert.Regexp(t, `(?m)^3\s+\S+\s+delete 2\s+\(undone\)$`, output)
+		assert.Regexp(t, `(?m)^2\s+\S+\s+add delete me\s*$`, output)
+		assert.Regexp(t, `(?m)^1\s+\S+\s+add keep me\s*$`, output)
+	})
+
+	t.Run("Redo", func(t *testing.T) {
+		output, err := runCommand(todoFile, "redo")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Redid: delete 2")
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.NotContains(t, output, "delete me")
+
+		_, err = runCommand(todoFile, "redo")
+		require.Error(t, err, "nothing left to redo")
+	})
+
+	t.Run("UndoAll", func(t *testing.T) {
+		for range 3 {
+			_, err := runCommand(todoFile, "undo")
+			require.NoError(t, err)
+		}
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "No tasks found.")
+
+		_, err = runCommand(todoFile, "undo")
+		require.Error(t, err, "nothing left to undo")
+	})
+
+	t.Run("HistoryFailureIsAWarning", func(t *testing.T) {
+		todoFile := filepath.Join(t.TempDir(), "todo.json")
+		// A directory where the journal should be can't be read or replaced.
+		require.NoError(t, os.Mkdir(filepath.Join(filepath.Dir(todoFile), "todo.journal.json"), 0755))
+
+		output, err := runCommand(todoFile, "add", "saved anyway")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Warning: the change was saved but can't be undone")
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "saved anyway")
+	})
+}
+
+func TestTodoCLIEdit(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	_, err := runCommand(todoFile, "add", "fix teh typo")
+	require.NoError(t, err)
+
+	t.Run("EditText", func(t *testing.T) {
+		output, err := runCommand(todoFile, "edit", "1", "fix the typo", "+docs")
+		require.NoError(t, err)
+		assert.Regexp(t, `Updated task \[[a-z]{4}\]: "fix the typo \+docs"`, output)
+
+		output, err = runCommand(todoFile, "list", "--project", "docs")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] fix the typo +docs\n", withoutIDs(output))
+	})
+
+	t.Run("EditMetadata", func(t *testing.T) {
+		_, err := runCommand(todoFile, "edit", "--priority", "A", "--due", "2000-01-01", "1")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] (A) fix the typo +docs (due 2000-01-01, OVERDUE)\n", withoutIDs(output))
+
+		_, err = runCommand(todoFile, "edit", "--due", "none", "1")
+		require.NoError(t, err)
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] (A) fix the typo +docs\n", withoutIDs(output))
+	})
+
+	t.Run("RejectBlankText", func(t *testing.T) {
+		_, err := runCommand(todoFile, "edit", "1", "   ")
+		require.Error(t, err)
+	})
+
+	t.Run("FlagsAfterTaskNumber", func(t *testing.T) {
+		// Flags after the task number change metadata and leave the text alone.
+		_, err := runCommand(todoFile, "edit", "1", "--due", "2000-01-02")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] (A) fix the typo +docs (due 2000-01-02, OVERDUE)\n", withoutIDs(output))
+
+		_, err = runCommand(todoFile, "edit", "1", "--due", "none")
+		require.NoError(t, err)
+
+		_, err = runCommand(todoFile, "edit", "1", "--due", "blah")
+		assert.Equal(t, 2, exitCode(t, err), "unparseable date")
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] (A) fix the typo +docs\n", withoutIDs(output))
+	})
+
+	t.Run("EditWithEditor", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("editor script requires a POSIX shell")
+		}
+
+		script := filepath.Join(t.TempDir(), "editor.sh")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nprintf 'edited in editor\\n' > \"$1\"\n"), 0755))
+
+		_, err := runCommandWithEnv(todoFile, []string{"VISUAL=", "EDITOR=" + script}, "edit", "--editor", "1")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] (A) edited in editor\n", withoutIDs(output))
+	})
+}
+
+func TestTodoCLIReopen(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	_, err := runCommand(todoFile, "add", "flaky task")
+	require.NoError(t, err)
+
+	t.Run("ReopenOpenTaskFails", func(t *testing.T) {
+		_, err := runCommand(todoFile, "reopen", "1")
+		require.Error(t, err)
+	})
+
+	t.Run("CompleteThenReopen", func(t *testing.T) {
+		_, err := runCommand(todoFile, "complete", "1")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "reopen", "1")
+		require.NoError(t, err)
+		assert.Regexp(t, `Reopened task #1 \[[a-z]{4}\]\.`, output)
+
+		output, err = runCommand(todoFile, "list", "--verbose")
+		require.NoError(t, err)
+		assert.Contains(t, output, "1. [ ] flaky task")
+		assert.Contains(t, output, "Reopened:")
+		assert.NotContains(t, output, "Completed:")
+	})
+
+	t.Run("Toggle", func(t *testing.T) {
+		output, err := runCommand(todoFile, "toggle", "1")
+		require.NoError(t, err)
+		assert.Contains(t, output, "as completed.")
+
+		output, err = runCommand(todoFile, "toggle", "1")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Reopened task #1")
+
+		output, err = runCommand(todoFile, "list", "--verbose")
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(output, "Reopened:"))
+	})
+}
+
+func TestTodoCLIBulkOperations(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	for n := 1; n <= 8; n++ {
+		task := fmt.Sprintf("task %d", n)
+		if n%2 == 0 {
+			task += " @errands"
+		}
+		_, err := runCommand(todoFile, "add", task)
+		require.NoError(t, err)
+	}
+
+	t.Run("InvalidReferenceLeavesListUnchanged", func(t *testing.T) {
+		_, err := runCommand(todoFile, "complete", "1", "99", "3")
+		require.Error(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.NotContains(t, output, "[x]")
+	})
+
+	t.Run("CompleteNumbersAndRanges", func(t *testing.T) {
+		output, err := runCommand(todoFile, "complete", "1", "3", "5-6")
+		require.NoError(t, err)
+		assert.Equal(t, 4, strings.Count(output, "as completed."))
+
+		output, err = runCommand(todoFile, "list", "--hide-completed")
+		require.NoError(t, err)
+		assert.Equal(t, "2. [ ] task 2 @errands\n4. [ ] task 4 @errands\n7. [ ] task 7\n8. [ ] task 8 @errands\n", withoutIDs(output))
+	})
+
+	t.Run("CompleteByTag", func(t *testing.T) {
+		output, err := runCommand(todoFile, "complete", "--tag", "@errands")
+		require.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(output, "as completed."), "already completed task 6 is skipped")
+	})
+
+	t.Run("RejectMixedSelectors", func(t *testing.T) {
+		_, err := runCommand(todoFile, "delete", "--done", "7")
+		require.Error(t, err)
+	})
+
+	t.Run("DeleteDone", func(t *testing.T) {
+		output, err := runCommand(todoFile, "delete", "--done")
+		require.NoError(t, err)
+		assert.Equal(t, 7, strings.Count(output, "Deleted task"))
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] task 7\n", withoutIDs(output))
+	})
+
+	t.Run("UndoBulkDeleteAsOneStep", func(t *testing.T) {
+		_, err := runCommand(todoFile, "undo")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, 8, strings.Count(output, "\n"))
+	})
+}
+
+func TestTodoCLIRecurring(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	t.Run("AddRecurringTask", func(t *testing.T) {
+		_, err := runCommand(todoFile, "add", "--every", "every 2 weeks", "--due", "2000-01-03", "review PRs")
+		require.NoError(t, err)
+
+		_, err = runCommand(todoFile, "add", "--every", "sometimes", "bad rule")
+		require.Error(t, err)
+	})
+
+	t.Run("VerboseShowsRule", func(t *testing.T) {
+		output, err := runCommand(todoFile, "list", "--verbose")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Repeats:\tevery 2 weeks")
+	})
+
+	t.Run("CompleteSpawnsNextOccurrence", func(t *testing.T) {
+		output, err := runCommand(todoFile, "complete", "1")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Scheduled next occurrence as task #2")
+
+		output, err = runCommand(todoFile, "list", "--hide-completed", "--overdue")
+		require.NoError(t, err)
+		assert.Contains(t, output, "No tasks to display.", "next occurrence should not be overdue")
+
+		output, err = runCommand(todoFile, "list", "--verbose", "--hide-completed")
+		require.NoError(t, err)
+		assert.Contains(t, output, "2. [ ] review PRs (due ")
+		assert.Contains(t, output, "Repeats:\tevery 2 weeks")
+	})
+
+	t.Run("ReopenAndCompleteDoesNotSpawnAgain", func(t *testing.T) {
+		_, err := runCommand(todoFile, "reopen", "1")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "complete", "1")
+		require.NoError(t, err)
+		assert.NotContains(t, output, "Scheduled next occurrence")
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.NotContains(t, output, "3. ")
+	})
+
+	t.Run("FlagsAfterTaskText", func(t *testing.T) {
+		_, err := runCommand(todoFile, "add", "water plants", "--every", "weekly")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list", "--verbose")
+		require.NoError(t, err)
+		assert.Contains(t, output, "3. [ ] water plants\n")
+		assert.Contains(t, output, "Repeats:\tweekly")
+	})
+}
+
+func TestTodoCLISubtasks(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	t.Run("AddSubtasks", func(t *testing.T) {
+		_, err := runCommand(todoFile, "add", "release v2")
+		require.NoError(t, err)
+		_, err = runCommand(todoFile, "add", "unrelated")
+		require.NoError(t, err)
+		_, err = runCommand(todoFile, "add", "--parent", "1", "write notes")
+		require.NoError(t, err)
+		_, err = runCommand(todoFile, "add", "--parent", "3", "proofread notes")
+		require.NoError(t, err)
+		_, err = runCommand(todoFile, "add", "--parent", "1", "tag build")
+		require.NoError(t, err)
+
+		_, err = runCommand(todoFile, "add", "--parent", "42", "orphan")
+		require.Error(t, err)
+	})
+
+	t.Run("ListRendersTree", func(t *testing.T) {
+		_, err := runCommand(todoFile, "complete", "5")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+
+		expected := "1. [ ] release v2 (1/3 done)\n" +
+			"  3. [ ] write notes (0/1 done)\n" +
+			"    4. [ ] proofread notes\n" +
+			"  5. [x] tag build\n" +
+			"2. [ ] unrelated\n"
+		assert.Equal(t, expected, withoutIDs(output))
+	})
+
+	t.Run("CompleteWithCascade", func(t *testing.T) {
+		output, err := runCommand(todoFile, "complete", "--cascade", "1")
+		require.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(output, "as completed."))
+
+		output, err = runCommand(todoFile, "list", "--hide-completed")
+		require.NoError(t, err)
+		assert.Equal(t, "2. [ ] unrelated\n", withoutIDs(output))
+	})
+
+	t.Run("DeleteParentKeepsSubtasks", func(t *testing.T) {
+		_, err := runCommand(todoFile, "delete", "3")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Contains(t, withoutIDs(output), "  3. [x] proofread notes\n")
+	})
+}
+
+func TestTodoCLITodoTxt(t *testing.T) {
+	dir := t.TempDir()
+	todoFile := filepath.Join(dir, "todo.json")
+	input := filepath.Join(dir, "todo.txt")
+
+	todoTxt := "(A) 2026-09-21 Call Bob +release @phone due:2026-10-01 id:kxbt\n" +
+		"x 2026-10-02 2026-09-20 Ship notes +release id:mnpq parent:kxbt\n"
+	require.NoError(t, os.WriteFile(input, []byte(todoTxt), 0644))
+
+	t.Run("Import", func(t *testing.T) {
+		output, err := runCommand(todoFile, "import", input)
+		require.NoError(t, err)
+		assert.Contains(t, output, "Imported 2 tasks.")
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "[kxbt] 1. [ ] (A) Call Bob +release @phone (1/1 done) (due 2026-10-01, OVERDUE)")
+		assert.Contains(t, output, "  [mnpq] 2. [x] Ship notes +release")
+	})
+
+	t.Run("ExportRoundTrip", func(t *testing.T) {
+		output, err := runCommand(todoFile, "export", "--format", "todotxt")
+		require.NoError(t, err)
+		assert.Equal(t, todoTxt, output)
+	})
+
+	t.Run("ImportFromStdin", func(t *testing.T) {
+		_, err := runCommandWithStdin(todoFile, "x Old chore\n", "import", "-")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "3. [x] Old chore")
+	})
+
+	t.Run("RejectUnknownFormat", func(t *testing.T) {
+		output, err := runCommand(todoFile, "export", "--format", "yaml")
+		assert.Equal(t, 2, exitCode(t, err))
+		assert.Contains(t, output, `unsupported export format "yaml"`)
+
+		output, err = runCommandWithStdin(todoFile, "x Old chore\n", "import", "--format", "xml", "-")
+		assert.Equal(t, 2, exitCode(t, err))
+		assert.Contains(t, output, `unsupported import format "xml"`)
+	})
+}
+
+func TestTodoCLIMarkdownAndCSV(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	checklist := `## Release checklist
+
+- [x] Bump version
+- [ ] Write changelog +release
+Some prose that is not a task.
+* [ ] Tag the build
+`
+
+	t.Run("ImportMarkdownFromStdin", func(t *testing.T) {
+		output, err := runCommandWithStdin(todoFile, checklist, "import", "--format", "markdown", "-")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Imported 3 tasks.")
+	})
+
+	t.Run("RejectMarkdownWithoutChecklist", func(t *testing.T) {
+		_, err := runCommandWithStdin(todoFile, "# Just a heading\n", "import", "--format", "markdown", "-")
+		require.Error(t, err)
+	})
+
+	t.Run("ExportMarkdown", func(t *testing.T) {
+		output, err := runCommand(todoFile, "export", "--format", "markdown")
+		require.NoError(t, err)
+		assert.Equal(t, "- [x] Bump version\n- [ ] Write changelog +release\n- [ ] Tag the build\n", output)
+	})
+
+	t.Run("ExportCSV", func(t *testing.T) {
+		output, err := runCommand(todoFile, "export", "--format", "csv")
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 4)
+		assert.True(t, strings.HasPrefix(lines[0], "id,task,done,"))
+		assert.Contains(t, lines[2], ",Write changelog +release,false,")
+	})
+
+	t.Run("ExportJSON", func(t *testing.T) {
+		output, err := runCommand(todoFile, "export", "--format", "json")
+		require.NoError(t, err)
+		assert.Contains(t, output, `"task": "Tag the build"`)
+	})
+}
+
+func TestTodoCLIMachineOutput(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	type result struct {
+		Command string `json:"command"`
+		Tasks   []struct {
+			Position  int       `json:"position"`
+			ID        string    `json:"id"`
+			Task      string    `json:"task"`
+			Done      bool      `json:"done"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"tasks"`
+		Error struct {
+			Type string `json:"type"`
+			Code int    `json:"code"`
+		} `json:"error"`
+	}
+
+	decode := func(t *testing.T, output string) result {
+		var r result
+		require.NoError(t, json.Unmarshal([]byte(output), &r), output)
+		return r
+	}
+
+	t.Run("AddJSON", func(t *testing.T) {
+		output, err := runCommand(todoFile, "--output", "json", "add", "Write report")
+		require.NoError(t, err)
+
+		r := decode(t, output)
+		assert.Equal(t, "add", r.Command)
+		require.Len(t, r.Tasks, 1)
+		assert.Equal(t, 1, r.Tasks[0].Position)
+		assert.Equal(t, "Write report", r.Tasks[0].Task)
+		assert.NotEmpty(t, r.Tasks[0].ID)
+		assert.False(t, r.Tasks[0].CreatedAt.IsZero())
+	})
+
+	t.Run("ListJSON", func(t *testing.T) {
+		_, err := runCommand(todoFile, "add", "Review PR")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "-o", "json", "list")
+		require.NoError(t, err)
+
+		r := decode(t, output)
+		require.Len(t, r.Tasks, 2)
+		assert.Equal(t, 2, r.Tasks[1].Position)
+		assert.Equal(t, "Review PR", r.Tasks[1].Task)
+	})
+
+	t.Run("CompleteJSON", func(t *testing.T) {
+		output, err := runCommand(todoFile, "-o", "json", "complete", "1")
+		require.NoError(t, err)
+
+		r := decode(t, output)
+		assert.Equal(t, "complete", r.Command)
+		require.Len(t, r.Tasks, 1)
+		assert.True(t, r.Tasks[0].Done)
+	})
+
+	t.Run("OutputAfterCommand", func(t *testing.T) {
+		output, err := runCommand(todoFile, "list", "--output", "json")
+		require.NoError(t, err)
+
+		r := decode(t, output)
+		assert.Equal(t, "list", r.Command)
+		require.Len(t, r.Tasks, 2)
+	})
+
+	t.Run("ListTSV", func(t *testing.T) {
+		output, err := runCommand(todoFile, "-o", "tsv", "list")
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, "position\tid\tdone\tpriority\tdue\tcreated_at\tcompleted_at\ttask", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "1\t"))
+		assert.True(t, strings.HasSuffix(lines[2], "\tReview PR"))
+	})
+
+	t.Run("DeleteJSON", func(t *testing.T) {
+		output, err := runCommand(todoFile, "-o", "json", "delete", "2")
+		require.NoError(t, err)
+
+		r := decode(t, output)
+		require.Len(t, r.Tasks, 1)
+		assert.Equal(t, "Review PR", r.Tasks[0].Task)
+	})
+
+	t.Run("ErrorObject", func(t *testing.T) {
+		output, err := runCommand(todoFile, "-o", "json", "complete", "9")
+		assert.Equal(t, 3, exitCode(t, err))
+
+		r := decode(t, output)
+		assert.Equal(t, "not_found", r.Error.Type)
+		assert.Equal(t, 3, r.Error.Code)
+	})
+
+	t.Run("RejectUnsupportedCommand", func(t *testing.T) {
+		output, err := runCommand(todoFile, "-o", "json", "toggle", "1")
+		assert.Equal(t, 2, exitCode(t, err))
+
+		r := decode(t, output)
+		assert.Equal(t, "usage", r.Error.Type)
+		assert.Contains(t, output, "--output json is not supported by toggle")
+
+		_, err = runCommand(todoFile, "agenda", "--output", "tsv")
+		assert.Equal(t, 2, exitCode(t, err))
+
+		_, err = runCommand(todoFile, "-o", "text", "agenda")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ExitCodes", func(t *testing.T) {
+		_, err := runCommand(todoFile, "delete")
+		assert.Equal(t, 2, exitCode(t, err), "missing arguments")
+
+		_, err = runCommand(todoFile, "--output", "xml", "list")
+		assert.Equal(t, 2, exitCode(t, err), "unknown output format")
+
+		_, err = runCommand(todoFile, "frobnicate")
+		assert.Equal(t, 2, exitCode(t, err), "unknown command")
+
+		_, err = runCommand(todoFile, "delete", "zzzz")
+		assert.Equal(t, 3, exitCode(t, err), "unknown ID")
+
+		_, err = runCommand(todoFile, "delete", "1-9")
+		assert.Equal(t, 3, exitCode(t, err), "range past the end of the list")
+
+		_, err = runCommand(todoFile, "delete", "3-1")
+		assert.Equal(t, 2, exitCode(t, err), "malformed range")
+
+		_, err = runCommand(t.TempDir(), "list")
+		assert.Equal(t, 4, exitCode(t, err), "unreadable todo file")
+	})
+}
+
+func TestTodoCLISearch(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	for _, task := range []string{"Write report +work", "Call Bob @phone", "Review report draft", "Buy milk"} {
+		_, err := runCommand(todoFile, "add", task)
+		require.NoError(t, err)
+	}
+	_, err := runCommand(todoFile, "complete", "3")
+	require.NoError(t, err)
+
+	t.Run("Substring", func(t *testing.T) {
+		output, err := runCommand(todoFile, "search", "REPORT")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Write report +work\n3. [x] Review report draft\n", withoutIDs(output))
+		assert.NotContains(t, output, "\x1b[", "no colors when not writing to a terminal")
+	})
+
+	t.Run("HideCompleted", func(t *testing.T) {
+		output, err := runCommand(todoFile, "search", "--hide-completed", "report")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Write report +work\n", withoutIDs(output))
+	})
+
+	t.Run("Regex", func(t *testing.T) {
+		output, err := runCommand(todoFile, "search", "--regex", `^(Call|Buy) `)
+		require.NoError(t, err)
+		assert.Equal(t, "2. [ ] Call Bob @phone\n4. [ ] Buy milk\n", withoutIDs(output))
+	})
+
+	t.Run("Fuzzy", func(t *testing.T) {
+		output, err := runCommand(todoFile, "search", "--fuzzy", "bmlk")
+		require.NoError(t, err)
+		assert.Equal(t, "4. [ ] Buy milk\n", withoutIDs(output))
+	})
+
+	t.Run("NoMatches", func(t *testing.T) {
+		output, err := runCommand(todoFile, "search", "dentist")
+		require.NoError(t, err)
+		assert.Contains(t, output, "No matching tasks.")
+	})
+
+	t.Run("RejectInvalidPatterns", func(t *testing.T) {
+		_, err := runCommand(todoFile, "search")
+		assert.Equal(t, 2, exitCode(t, err))
+
+		_, err = runCommand(todoFile, "search", "--regex", "(")
+		assert.Equal(t, 2, exitCode(t, err))
+
+		_, err = runCommand(todoFile, "search", "--regex", "--fuzzy", "x")
+		assert.Equal(t, 2, exitCode(t, err))
+	})
+}
+
+func TestTodoCLINamedLists(t *testing.T) {
+	home := t.TempDir()
+	env := []string{"HOME=" + home, "TODOG_FILE=", "TODOG_LIST="}
+
+	// Run from the temporary home so no project list is discovered.
+	run := func(args ...string) (string, error) {
+		return runCommandInDir(home, env, args...)
+	}
+
+	t.Run("DefaultListUsesLegacyFile", func(t *testing.T) {
+		_, err := run("add", "Buy milk")
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(home, ".todog", "todo.json"))
+	})
+
+	t.Run("AddToNamedList", func(t *testing.T) {
+		_, err := run("--list", "work", "add", "Write report")
+		require.NoError(t, err)
+		_, err = run("-l", "work", "add", "Review PR")
+		require.NoError(t, err)
+
+		output, err := run("--list", "work", "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Write report\n2. [ ] Review PR\n", withoutIDs(output))
+
+		output, err = run("list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Buy milk\n", withoutIDs(output))
+	})
+
+	t.Run("EnumerateLists", func(t *testing.T) {
+		output, err := run("lists")
+		require.NoError(t, err)
+		assert.Equal(t, "* todo (1 open)\n  work (2 open)\n", output)
+	})
+
+	t.Run("MoveTaskToList", func(t *testing.T) {
+		_, err := run("--list", "work", "add", "--parent", "1", "Outline")
+		require.NoError(t, err)
+
+		output, err := run("--list", "work", "move", "--to", "personal", "1")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Moved task #1")
+		assert.Contains(t, output, "to personal as #2")
+
+		output, err = run("--list", "personal", "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Write report (0/1 done)\n  2. [ ] Outline\n", withoutIDs(output))
+
+		output, err = run("--list", "work", "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Review PR\n", withoutIDs(output))
+	})
+
+	t.Run("MoveTaskToListWithTrailingTo", func(t *testing.T) {
+		_, err := run("--list", "work", "add", "Draft agenda")
+		require.NoError(t, err)
+
+		output, err := run("--list", "work", "move", "2", "--to", "personal")
+		require.NoError(t, err)
+		assert.Contains(t, output, "to personal as #3")
+
+		output, err = run("--list", "work", "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Review PR\n", withoutIDs(output))
+	})
+
+	t.Run("SetDefaultList", func(t *testing.T) {
+		_, err := run("lists", "--set-default", "work")
+		require.NoError(t, err)
+
+		output, err := run("list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Review PR\n", withoutIDs(output))
+
+		output, err = runCommandInDir(home, append(env, "TODOG_LIST=personal"), "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Write report", "TODOG_LIST overrides the saved default")
+	})
+
+	t.Run("RejectInvalidNames", func(t *testing.T) {
+		_, err := run("--list", "../escape", "list")
+		assert.Equal(t, 2, exitCode(t, err))
+
+		_, err = run("move", "--to", "work", "1")
+		assert.Equal(t, 2, exitCode(t, err), "tasks are already in work")
+
+		_, err = run("move", "1")
+		assert.Equal(t, 2, exitCode(t, err), "missing --to")
+	})
+}
+
+func TestTodoCLIProjectLists(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	nested := filepath.Join(project, "src", "pkg")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+
+	env := []string{"HOME=" + home, "TODOG_FILE=", "TODOG_LIST="}
+
+	t.Run("FallBackToHomeList", func(t *testing.T) {
+		output, err := runCommandInDir(nested, env, "where")
+		require.NoError(t, err)
+		assert.Contains(t, output, filepath.Join(home, ".todog", "todo.json"))
+		assert.Contains(t, output, "(the default list)")
+	})
+
+	t.Run("Init", func(t *testing.T) {
+		output, err := runCommandInDir(project, env, "init")
+		require.NoError(t, err)
+		assert.Contains(t, output, filepath.Join(project, ".todog.json"))
+		assert.FileExists(t, filepath.Join(project, ".todog.json"))
+
+		_, err = runCommandInDir(project, env, "init")
+		assert.Error(t, err, "init should not overwrite an existing list")
+	})
+
+	t.Run("DiscoverFromSubdirectory", func(t *testing.T) {
+		_, err := runCommandInDir(nested, env, "add", "Fix the build")
+		require.NoError(t, err)
+
+		output, err := runCommandInDir(project, env, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Fix the build\n", withoutIDs(output))
+
+		output, err = runCommandInDir(nested, env, "where")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(output, filepath.Join(project, ".todog.json")+"\n"))
+		assert.Contains(t, output, "nearest project list")
+
+		output, err = runCommandInDir(home, env, "list")
+		require.NoError(t, err)
+		assert.Contains(t, output, "No tasks found.", "home list is unaffected")
+	})
+
+	t.Run("ProjectDirectory", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(filepath.Join(nested, ".todog"), 0755))
+
+		output, err := runCommandInDir(nested, env, "where")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(output, filepath.Join(nested, ".todog", "todo.json")+"\n"))
+	})
+
+	t.Run("ExplicitChoicesWin", func(t *testing.T) {
+		output, err := runCommandInDir(project, env, "--list", "work", "where")
+		require.NoError(t, err)
+		assert.Contains(t, output, filepath.Join(home, ".todog", "work.json"))
+		assert.Contains(t, output, "(selected with --list)")
+
+		output, err = runCommandInDir(project, append(env, "TODOG_LIST=personal"), "where")
+		require.NoError(t, err)
+		assert.Contains(t, output, "(set by TODOG_LIST)")
+	})
+}
+
+func TestTodoCLIConfig(t *testing.T) {
+	home := t.TempDir()
+	todoFile := filepath.Join(home, "todo.json")
+	configFile := filepath.Join(home, "config.yaml")
+
+	env := []string{"TODOG_CONFIG=" + configFile}
+	run := func(args ...string) (string, error) {
+		return runCommandWithEnv(todoFile, env, args...)
+	}
+
+	for _, task := range []string{"Write report", "Call Bob"} {
+		_, err := run("add", task)
+		require.NoError(t, err)
+	}
+	_, err := run("complete", "1")
+	require.NoError(t, err)
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		_, err := run("config", "set", "list.hide_completed", "true")
+		require.NoError(t, err)
+
+		output, err := run("config", "get", "list.hide_completed")
+		require.NoError(t, err)
+		assert.Equal(t, "true\n", output)
+
+		data, err := os.ReadFile(configFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "hide_completed: true")
+	})
+
+	t.Run("ConfigDefaultsApplyToList", func(t *testing.T) {
+		output, err := run("list")
+		require.NoError(t, err)
+		assert.Equal(t, "2. [ ] Call Bob\n", withoutIDs(output))
+	})
+
+	t.Run("FlagOverridesConfig", func(t *testing.T) {
+		output, err := run("list", "--hide-completed=false")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [x] Write report\n2. [ ] Call Bob\n", withoutIDs(output))
+	})
+
+	t.Run("DateFormat", func(t *testing.T) {
+		_, err := run("config", "set", "date_format", "Jan 2, 2006")
+		require.NoError(t, err)
+
+		output, err := run("list", "--verbose")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Created:\t"+time.Now().Format("Jan 2, 2006"))
+	})
+
+	t.Run("Aliases", func(t *testing.T) {
+		_, err := run("config", "set", "aliases.open", "list --sort due")
+		require.NoError(t, err)
+
+		output, err := run("open")
+		require.NoError(t, err)
+		assert.Equal(t, "2. [ ] Call Bob\n", withoutIDs(output))
+
+		_, err = run("config", "set", "aliases.list", "agenda")
+		assert.Equal(t, 2, exitCode(t, err), "builtin commands can't be redefined")
+	})
+
+	t.Run("ListAndUnset", func(t *testing.T) {
+		output, err := run("config", "list")
+		require.NoError(t, err)
+		assert.Equal(t, "date_format=Jan 2, 2006\nlist.hide_completed=true\naliases.open=list --sort due\n", output)
+
+		_, err = run("config", "unset", "list.hide_completed")
+		require.NoError(t, err)
+
+		_, err = run("config", "get", "list.hide_completed")
+		assert.Equal(t, 1, exitCode(t, err), "unset keys have no value")
+	})
+
+	t.Run("EnvironmentOverridesConfig", func(t *testing.T) {
+		other := filepath.Join(home, "other.json")
+		_, err := run("config", "set", "file", other)
+		require.NoError(t, err)
+
+		// TODOG_FILE is set by the test helper, so it wins over the setting.
+		output, err := run("where")
+		require.NoError(t, err)
+		assert.Contains(t, output, "(set by TODOG_FILE)")
+
+		output, err = runCommandWithEnv("", env, "where")
+		require.NoError(t, err)
+		assert.Contains(t, output, other)
+	})
+
+	t.Run("FixInvalidDefaultList", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(configFile, []byte("default_list: my list\n"), 0644))
+
+		_, err := run("list")
+		assert.Equal(t, 2, exitCode(t, err), "invalid default_list")
+
+		_, err = run("config", "set", "default_list", "work")
+		require.NoError(t, err)
+		output, err := run("config", "get", "default_list")
+		require.NoError(t, err)
+		assert.Equal(t, "work\n", output)
+
+		require.NoError(t, os.WriteFile(configFile, []byte("default_list: my list\n"), 0644))
+		_, err = run("config", "unset", "default_list")
+		require.NoError(t, err)
+		_, err = run("list")
+		require.NoError(t, err)
+	})
+
+	t.Run("RejectInvalidSettings", func(t *testing.T) {
+		_, err := run("config", "set", "color", "sometimes")
+		assert.Equal(t, 2, exitCode(t, err))
+
+		_, err = run("config", "set", "no_such_key", "x")
+		assert.Equal(t, 2, exitCode(t, err))
+
+		require.NoError(t, os.WriteFile(configFile, []byte("list: [\n"), 0644))
+		output, err := run("list")
+		assert.Equal(t, 1, exitCode(t, err), "malformed config")
+		assert.Contains(t, output, configFile)
+	})
+
+	t.Run("SetReplacesMalformedConfig", func(t *testing.T) {
+		output, err := run("config", "set", "color", "never")
+		require.NoError(t, err)
+		assert.Contains(t, output, "moved it to "+configFile+".bak")
+		assert.FileExists(t, configFile+".bak")
+
+		output, err = run("config", "list")
+		require.NoError(t, err)
+		assert.Equal(t, "color=never\n", output)
+	})
+}
+
+func TestTodoCLIArchive(t *testing.T) {
+	dir := t.TempDir()
+	todoFile := filepath.Join(dir, "todo.json")
+	archiveFile := filepath.Join(dir, "todo.archive.jsonl")
+
+	old := time.Now().AddDate(0, 0, -45).UTC().Format(time.RFC3339)
+	recent := time.Now().UTC().Format(time.RFC3339)
+	data := fmt.Sprintf(`[
+  {"id": "aaaa", "task": "Old done", "done": true, "created_at": %[1]q, "completed_at": %[1]q},
+  {"id": "bbbb", "task": "Recent done", "done": true, "created_at": %[2]q, "completed_at": %[2]q},
+  {"id": "cccc", "task": "Still open", "done": false, "created_at": %[2]q, "completed_at": "0001-01-01T00:00:00Z"}
+]`, old, recent)
+	require.NoError(t, os.WriteFile(todoFile, []byte(data), 0644))
+
+	t.Run("ArchiveOlderThan", func(t *testing.T) {
+		output, err := runCommand(todoFile, "archive", "--older-than", "30d")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Archived 1 task to ")
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [x] Recent done\n2. [ ] Still open\n", withoutIDs(output))
+	})
+
+	t.Run("ListArchived", func(t *testing.T) {
+		output, err := runCommand(todoFile, "list", "--archived", "--verbose")
+		require.NoError(t, err)
+		assert.Contains(t, output, "[aaaa] 1. [x] Old done")
+		assert.Contains(t, output, "Completed:\t"+old, "completion time is kept")
+	})
+
+	t.Run("ArchiveAllCompleted", func(t *testing.T) {
+		_, err := runCommand(todoFile, "archive")
+		require.NoError(t, err)
+
+		output, err := runCommand(todoFile, "list", "--archived")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [x] Old done\n2. [x] Recent done\n", withoutIDs(output))
+
+		output, err = runCommand(todoFile, "archive")
+		require.NoError(t, err)
+		assert.Contains(t, output, "No completed tasks to archive.")
+	})
+
+	t.Run("ArchiveCannotBeUndone", func(t *testing.T) {
+		output, err := runCommand(todoFile, "undo")
+		require.Error(t, err)
+		assert.Contains(t, output, `"archive" can't be undone`)
+
+		output, err = runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Still open\n", withoutIDs(output))
+	})
+
+	t.Run("ArchiveIsAppendOnly", func(t *testing.T) {
+		data, err := os.ReadFile(archiveFile)
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `"id":"aaaa"`)
+		assert.Contains(t, lines[1], `"id":"bbbb"`)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		output, err := runCommand(todoFile, "stats")
+		require.NoError(t, err)
+		assert.Regexp(t, `(?m)^Open:\s+1$`, output)
+		assert.Regexp(t, `(?m)^Completed:\s+0$`, output)
+		assert.Regexp(t, `(?m)^Archived:\s+2$`, output)
+	})
+}
+
+func TestTodoCLIStats(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	now := time.Now()
+	day := func(offset int) string {
+		return now.AddDate(0, 0, offset).UTC().Format(time.RFC3339)
+	}
+	data := fmt.Sprintf(`[
+  {"id": "aaaa", "task": "One", "done": true, "created_at": %q, "completed_at": %q},
+  {"id": "bbbb", "task": "Two", "done": true, "created_at": %q, "completed_at": %q},
+  {"id": "cccc", "task": "Three", "done": false, "created_at": %q, "completed_at": "0001-01-01T00:00:00Z"}
+]`, day(-3), day(-1), day(-2), day(0), day(0))
+	require.NoError(t, os.WriteFile(todoFile, []byte(data), 0644))
+
+	t.Run("Text", func(t *testing.T) {
+		output, err := runCommand(todoFile, "stats", "--by", "day", "--last", "4")
+		require.NoError(t, err)
+
+		assert.Regexp(t, `(?m)^Median time to complete:\s+2d 0h$`, output)
+		assert.Regexp(t, `(?m)^Current streak:\s+2 days$`, output)
+		assert.Regexp(t, `(?m)^\s*`+now.Format("2006-01-02")+`\s+1\s+1\s+1$`, output)
+		assert.Regexp(t, `(?m)^Completed\s+▁▁██$`, output)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		output, err := runCommand(todoFile, "--output", "json", "stats", "--by", "day", "--last", "4")
+		require.NoError(t, err)
+
+		var stats struct {
+			Open          int     `json:"open"`
+			Completed     int     `json:"completed"`
+			MedianSeconds float64 `json:"median_time_to_complete_seconds"`
+			Periods       []struct {
+				Created   int `json:"created"`
+				Completed int `json:"completed"`
+				Open      int `json:"open"`
+			} `json:"periods"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(output), &stats), output)
+
+		assert.Equal(t, 1, stats.Open)
+		assert.Equal(t, 2, stats.Completed)
+		assert.InDelta(t, 2*24*3600, stats.MedianSeconds, 1)
+		require.Len(t, stats.Periods, 4)
+		assert.Equal(t, []int{1, 1, 1, 1}, []int{
+			stats.Periods[0].Created, stats.Periods[1].Created, stats.Periods[2].Completed, stats.Periods[3].Completed,
+		})
+	})
+
+	t.Run("RejectInvalidPeriod", func(t *testing.T) {
+		_, err := runCommand(todoFile, "stats", "--by", "month")
+		assert.Equal(t, 2, exitCode(t, err))
+	})
+}
+
+func TestTodoCLITimeTracking(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	_, err := runCommand(todoFile, "add", "Write report +work")
+	require.NoError(t, err)
+	_, err = runCommand(todoFile, "add", "Review PR +work @office")
+	require.NoError(t, err)
+
+	t.Run("StartAndSwitch", func(t *testing.T) {
+		output, err := runCommand(todoFile, "start", "1")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Started tracking task #1 \[[a-z]+\]\.\n$`, output)
+
+		output, err = runCommand(todoFile, "start", "2")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Stopped tracking task #1 \[[a-z]+\] after <1m\.\nStarted tracking task #2 \[[a-z]+\]\.\n$`, output)
+
+		output, err = runCommand(todoFile, "list", "--verbose")
+		require.NoError(t, err)
+		assert.Regexp(t, `(?m)^    Tracked:\s+<1m \(running\)$`, output)
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		output, err := runCommand(todoFile, "stop")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Stopped tracking task #2 \[[a-z]+\] after <1m\.\n$`, output)
+
+		_, err = runCommand(todoFile, "stop")
+		assert.Equal(t, 1, exitCode(t, err))
+	})
+
+	t.Run("RejectCompletedTask", func(t *testing.T) {
+		_, err := runCommand(todoFile, "complete", "1")
+		require.NoError(t, err)
+
+		_, err = runCommand(todoFile, "start", "1")
+		assert.Equal(t, 1, exitCode(t, err))
+
+		_, err = runCommand(todoFile, "start", "9")
+		assert.Equal(t, 3, exitCode(t, err))
+	})
+
+	t.Run("Timesheet", func(t *testing.T) {
+		// Log two hours on task 2 earlier today.
+		data, err := os.ReadFile(todoFile)
+		require.NoError(t, err)
+		var items []map[string]any
+		require.NoError(t, json.Unmarshal(data, &items))
+		end := time.Now().Add(-time.Minute)
+		items[1]["time_log"] = []map[string]string{{
+			"start": end.Add(-2 * time.Hour).Format(time.RFC3339),
+			"end":   end.Format(time.RFC3339),
+		}}
+		data, err = json.Marshal(items)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(todoFile, data, 0644))
+
+		output, err := runCommand(todoFile, "timesheet")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Timesheet for all time\n")
+		assert.Regexp(t, `(?m)^2\.00\s+\[[a-z]+\] Review PR \+work @office$`, output)
+		assert.Regexp(t, `(?m)^2\.00\s+Total$`, output)
+		assert.Regexp(t, `(?m)^2\.00\s+@office$`, output)
+
+		output, err = runCommand(todoFile, "--output", "json", "timesheet", "--since", "today")
+		require.NoError(t, err)
+		var sheet struct {
+			TotalHours float64 `json:"total_hours"`
+			Tags       []struct {
+				Tag   string  `json:"tag"`
+				Hours float64 `json:"hours"`
+			} `json:"tags"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(output), &sheet), output)
+		if end.Add(-2*time.Hour).YearDay() == time.Now().YearDay() {
+			assert.Equal(t, 2.0, sheet.TotalHours)
+			require.Len(t, sheet.Tags, 2)
+			assert.Equal(t, "+work", sheet.Tags[0].Tag)
+		}
+
+		_, err = runCommand(todoFile, "timesheet", "--week", "--since", "today")
+		assert.Equal(t, 2, exitCode(t, err))
+	})
+}
+
+func TestTodoCLINotes(t *testing.T) {
+	dir := t.TempDir()
+	todoFile := filepath.Join(dir, "todo.json")
+
+	_, err := runCommand(todoFile, "add", "--priority", "B", "Fix bug +core")
+	require.NoError(t, err)
+
+	t.Run("AddNote", func(t *testing.T) {
+		output, err := runCommand(todoFile, "note", "1", "Happens", "on empty files")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Added note to task #1 \[[a-z]+\]\.\n$`, output)
+
+		_, err = runCommand(todoFile, "note", "1")
+		assert.Equal(t, 2, exitCode(t, err))
+		_, err = runCommand(todoFile, "note", "9", "Missing")
+		assert.Equal(t, 3, exitCode(t, err))
+	})
+
+	t.Run("Attach", func(t *testing.T) {
+		attachment := filepath.Join(dir, "trace.log")
+		require.NoError(t, os.WriteFile(attachment, nil, 0644))
+
+		output, err := runCommand(todoFile, "attach", "1", attachment, "https://example.com/issues/7", filepath.Join(dir, "gone.txt"))
+		require.NoError(t, err)
+		assert.Contains(t, output, "Attached https://example.com/issues/7 to task #1")
+
+		_, err = runCommand(todoFile, "attach", "1", attachment)
+		assert.Equal(t, 1, exitCode(t, err))
+	})
+
+	t.Run("Show", func(t *testing.T) {
+		output, err := runCommand(todoFile, "show", "1")
+		require.NoError(t, err)
+
+		assert.Regexp(t, `(?m)^Task:\s+Fix bug \+core$`, output)
+		assert.Regexp(t, `(?m)^Priority:\s+B$`, output)
+		assert.Regexp(t, `(?m)^Tags:\s+\+core$`, output)
+		assert.Regexp(t, `(?m)^Notes:\n  \S+\n    Happens on empty files$`, output)
+		assert.Contains(t, output, "\nAttachments:\n  "+filepath.Join(dir, "trace.log")+"\n")
+		assert.Contains(t, output, "  https://example.com/issues/7\n")
+		assert.Contains(t, output, "  "+filepath.Join(dir, "gone.txt")+" (missing)\n")
+
+		output, err = runCommand(todoFile, "--output", "json", "show", "1")
+		require.NoError(t, err)
+		var result struct {
+			Tasks []struct {
+				Notes []struct {
+					Text string `json:"text"`
+				} `json:"notes"`
+				Attachments []string `json:"attachments"`
+			} `json:"tasks"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(output), &result), output)
+		require.Len(t, result.Tasks, 1)
+		assert.Equal(t, "Happens on empty files", result.Tasks[0].Notes[0].Text)
+		assert.Len(t, result.Tasks[0].Attachments, 3)
+
+		_, err = runCommand(todoFile, "show", "9")
+		assert.Equal(t, 3, exitCode(t, err))
+	})
+
+	t.Run("Detach", func(t *testing.T) {
+		output, err := runCommand(todoFile, "detach", "1", "https://example.com/issues/7")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Removed https://example.com/issues/7 from task #1 \[[a-z]+\]\.\n$`, output)
+
+		output, err = runCommand(todoFile, "show", "1")
+		require.NoError(t, err)
+		assert.NotContains(t, output, "example.com")
+
+		_, err = runCommand(todoFile, "detach", "1", "https://example.com/issues/7")
+		assert.Equal(t, 1, exitCode(t, err))
+	})
+}
+
+func TestTodoCLIReorder(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	for _, task := range []string{"banana", "Apple", "cherry"} {
+		_, err := runCommand(todoFile, "add", task)
+		require.NoError(t, err)
+	}
+
+	list := func(t *testing.T) string {
+		t.Helper()
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		return withoutIDs(output)
+	}
+
+	t.Run("Move", func(t *testing.T) {
+		output, err := runCommand(todoFile, "move", "3", "1")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Moved task #3 \[[a-z]+\] to position 1\.\n$`, output)
+		assert.Equal(t, "1. [ ] cherry\n2. [ ] banana\n3. [ ] Apple\n", list(t))
+
+		output, err = runCommand(todoFile, "move", "1", "99")
+		assert.Equal(t, 2, exitCode(t, err))
+		assert.Contains(t, output, "position 99 is out of range (1-3)")
+		_, err = runCommand(todoFile, "move", "1", "last")
+		assert.Equal(t, 2, exitCode(t, err))
+		output, err = runCommand(todoFile, "move", "1")
+		assert.Equal(t, 2, exitCode(t, err))
+		assert.Contains(t, output, "please provide a task number or ID and its new position")
+		output, err = runCommand(todoFile, "move", "--tag", "fruit")
+		assert.Equal(t, 2, exitCode(t, err))
+		assert.Contains(t, output, "please add --to LIST")
+	})
+
+	t.Run("TopAndBottom", func(t *testing.T) {
+		_, err := runCommand(todoFile, "top", "3")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] Apple\n2. [ ] cherry\n3. [ ] banana\n", list(t))
+
+		output, err := runCommand(todoFile, "bottom", "1")
+		require.NoError(t, err)
+		assert.Regexp(t, `^Moved task #1 \[[a-z]+\] to position 3\.\n$`, output)
+		assert.Equal(t, "1. [ ] cherry\n2. [ ] banana\n3. [ ] Apple\n", list(t))
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		output, err := runCommand(todoFile, "sort", "--by", "text")
+		require.NoError(t, err)
+		assert.Equal(t, "Sorted 3 tasks by text.\n", output)
+		assert.Equal(t, "1. [ ] Apple\n2. [ ] banana\n3. [ ] cherry\n", list(t))
+
+		_, err = runCommand(todoFile, "complete", "1")
+		require.NoError(t, err)
+		_, err = runCommand(todoFile, "sort", "--by", "done")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] banana\n2. [ ] cherry\n3. [x] Apple\n", list(t))
+
+		_, err = runCommand(todoFile, "sort", "--by", "created")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] banana\n2. [x] Apple\n3. [ ] cherry\n", list(t))
+
+		_, err = runCommand(todoFile, "sort", "--by", "priority")
+		assert.Equal(t, 2, exitCode(t, err))
+		_, err = runCommand(todoFile, "sort")
+		assert.Equal(t, 2, exitCode(t, err))
+	})
+
+	t.Run("Undo", func(t *testing.T) {
+		_, err := runCommand(todoFile, "undo")
+		require.NoError(t, err)
+		assert.Equal(t, "1. [ ] banana\n2. [ ] cherry\n3. [x] Apple\n", list(t))
+	})
+}
+
+func TestTodoCLIServe(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.json")
+
+	cmd := exec.Command(binPath, "serve", "--addr", "127.0.0.1:0")
+	cmd.Env = append(os.Environ(), "TODOG_FILE="+todoFile)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	// The first line reports the address, which has a random port.
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+	match := regexp.MustCompile(`on (http://\S+) `).FindStringSubmatch(line)
+	require.NotNil(t, match, line)
+	url := match[1] + "/tasks"
+
+	t.Run("ConcurrentWithCLI", func(t *testing.T) {
+		const n = 10
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				resp, err := http.Post(url, "application/json", strings.NewReader(fmt.Sprintf(`{"task": "api %d"}`, i)))
+				if assert.NoError(t, err) {
+					resp.Body.Close()
+					assert.Equal(t, http.StatusCreated, resp.StatusCode)
+				}
+			}(i)
+			go func(i int) {
+				defer wg.Done()
+				_, err := runCommand(todoFile, "add", fmt.Sprintf("cli %d", i))
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		// Nothing is lost when both write the file at once.
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var result struct {
+			Tasks []struct {
+				Task string `json:"task"`
+			} `json:"tasks"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Len(t, result.Tasks, 2*n)
+	})
+
+	t.Run("ChangesAreJournaled", func(t *testing.T) {
+		req, err := http.NewRequest("PATCH", url+"/1", strings.NewReader(`{"done": true}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		output, err := runCommand(todoFile, "list")
+		require.NoError(t, err)
+		assert.Regexp(t, `(?m)^\[[a-z]+\] 1\. \[x\] `, output)
+
+		output, err = runCommand(todoFile, "undo")
+		require.NoError(t, err)
+		assert.Equal(t, "Undid: PATCH /tasks/1\n", output)
+	})
+
+	t.Run("RejectArguments", func(t *testing.T) {
+		_, err := runCommand(todoFile, "serve", "now")
+		assert.Equal(t, 2, exitCode(t, err))
+	})
+}
+
+// withoutIDs strips the leading "[abcd] " ID column from list output.
+func withoutIDs(output string) string {
+	return regexp.MustCompile(`(?m)^(\s*)\[[a-z]+\] `).ReplaceAllString(output, "$1")
+}
+
+// exitCode returns the exit status of a failed command.
+func exitCode(t *testing.T, err error) int {
+	t.Helper()
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	return exitErr.ExitCode()
+}
+
 func runCommand(todoFile string, args ...string) (string, error) {
 	cmd := exec.Command(binPath, args...)
 	cmd.Env = append(os.Environ(), "TODOG_FILE="+todoFile)
@@ -142,6 +1817,23 @@ func runCommand(todoFile string, args ...string) (string, error) {
 	return string(out), err
 }
 
+func runCommandWithEnv(todoFile string, env []string, args ...string) (string, error) {
+	cmd := exec.Command(binPath, args...)
+	cmd.Env = append(append(os.Environ(), "TODOG_FILE="+todoFile), env...)
+
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func runCommandInDir(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command(binPath, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
 func runCommandWithStdin(todoFile, stdin string, args ...string) (string, error) {
 	cmd := exec.Command(binPath, args...)
 	cmd.Env = append(os.Environ(), "TODOG_FILE="+todoFile)
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// reorderFlags moves the flags given after a command's arguments to before
+// them, since urfave/cli stops parsing flags at the first argument. Without
+// it, "todog add ship release --due friday" would add a task called "ship
+// release --due friday". Global flags given after the command, as in "todog
+// list --output json", move before the command unless the command has a
+// flag of the same name. Words that only look like flags, such as the
+// "-urgent" in "todog add call mom -urgent", and anything after "--" are
+// left as arguments.
+func reorderFlags(app *cli.App, args []string) []string {
+	i := 1
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args
+		}
+		if !isFlag(arg) {
+			break
+		}
+		// Skip the value of a global flag given as a separate argument.
+		if !strings.Contains(arg, "=") && flagTakesValue(app.Flags, arg) {
+			i++
+		}
+	}
+	if i >= len(args) {
+		return args
+	}
+
+	cmd := app.Command(args[i])
+	if cmd == nil {
+		return args
+	}
+	i++
+
+	if i < len(args) && len(cmd.Subcommands) > 0 {
+		if sub := cmd.Command(args[i]); sub != nil {
+			cmd = sub
+			i++
+		}
+	}
+
+	var global, flags, positional []string
+	for j := i; j < len(args); j++ {
+		arg := args[j]
+		if arg == "--" {
+			positional = append(positional, args[j:]...)
+			break
+		}
+		if !isFlag(arg) || !hasFlag(cmd.Flags, arg) && !hasFlag(app.Flags, arg) && !hasFlag(helpFlags, arg) {
+			positional = append(positional, arg)
+			continue
+		}
+
+		owner := cmd.Flags
+		dest := &flags
+		if !hasFlag(cmd.Flags, arg) && hasFlag(app.Flags, arg) {
+			owner = app.Flags
+			dest = &global
+		}
+
+		*dest = append(*dest, arg)
+		if !strings.Contains(arg, "=") && flagTakesValue(owner, arg) && j+1 < len(args) {
+			j++
+			*dest = append(*dest, args[j])
+		}
+	}
+
+	reordered := append([]string{args[0]}, global...)
+	reordered = append(reordered, args[1:i]...)
+	reordered = append(reordered, flags...)
+	return append(reordered, positional...)
+}
+
+// helpFlags holds the --help flag, which urfave/cli only adds to commands
+// once the app runs.
+var helpFlags = []cli.Flag{cli.HelpFlag}
+
+// isFlag reports whether arg looks like a flag. A lone "-" is an argument,
+// usually meaning standard input.
+func isFlag(arg string) bool {
+	return len(arg) > 1 && strings.HasPrefix(arg, "-")
+}
+
+// flagTakesValue reports whether arg names one of flags that needs a value.
+func flagTakesValue(flags []cli.Flag, arg string) bool {
+	if f, ok := findFlag(flags, arg).(cli.DocGenerationFlag); ok {
+		return f.TakesValue()
+	}
+	return false
+}
+
+// hasFlag reports whether arg names one of flags.
+func hasFlag(flags []cli.Flag, arg string) bool {
+	return findFlag(flags, arg) != nil
+}
+
+// findFlag returns the flag arg names, ignoring any "=value", or nil.
+func findFlag(flags []cli.Flag, arg string) cli.Flag {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+	for _, flag := range flags {
+		if slices.Contains(flag.Names(), name) {
+			return flag
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestReorderFlags(t *testing.T) {
+	app := &cli.App{
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "list", Aliases: []string{"l"}},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "search",
+				Flags: []cli.Flag{&cli.BoolFlag{Name: "list"}},
+			},
+			{
+				Name: "add",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "due"},
+					&cli.BoolFlag{Name: "multiline"},
+				},
+			},
+			{
+				Name:        "config",
+				Subcommands: []*cli.Command{{Name: "set"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			[]string{"todog", "add", "ship", "release", "--due", "friday"},
+			[]string{"todog", "add", "--due", "friday", "ship", "release"},
+		},
+		{
+			[]string{"todog", "-l", "work", "add", "ship", "--due=friday", "--multiline"},
+			[]string{"todog", "-l", "work", "add", "--due=friday", "--multiline", "ship"},
+		},
+		{
+			// Global flags after the command move before it.
+			[]string{"todog", "add", "ship", "--output", "json", "-l=work", "--due", "friday"},
+			[]string{"todog", "--output", "json", "-l=work", "add", "--due", "friday", "ship"},
+		},
+		{
+			// A command's own flag wins over a global one of the same name.
+			[]string{"todog", "search", "milk", "--list"},
+			[]string{"todog", "search", "--list", "milk"},
+		},
+		{
+			// Words that aren't flags stay in the task text.
+			[]string{"todog", "add", "call", "mom", "-urgent", "--due", "friday"},
+			[]string{"todog", "add", "--due", "friday", "call", "mom", "-urgent"},
+		},
+		{
+			[]string{"todog", "add", "ship", "--help"},
+			[]string{"todog", "add", "--help", "ship"},
+		},
+		{
+			// Everything after "--" stays an argument.
+			[]string{"todog", "add", "ship", "--", "--due", "friday"},
+			[]string{"todog", "add", "ship", "--", "--due", "friday"},
+		},
+		{
+			[]string{"todog", "config", "set", "color", "never"},
+			[]string{"todog", "config", "set", "color", "never"},
+		},
+		{
+			[]string{"todog", "unknown", "x", "--due", "friday"},
+			[]string{"todog", "unknown", "x", "--due", "friday"},
+		},
+		{
+			[]string{"todog", "add", "-"},
+			[]string{"todog", "add", "-"},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, reorderFlags(app, tt.args))
+	}
+}
@@ -2,16 +2,27 @@ package cli
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/mnishiguchi/command-line-go/todog/internal/server"
 	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+	"github.com/mnishiguchi/command-line-go/todog/internal/tui"
 	"github.com/urfave/cli/v2"
 )
 
@@ -19,60 +30,248 @@ func Execute(version string) {
 	log.SetFlags(0)
 	logger := log.New(os.Stderr, "", 0)
 
+	// format is the validated --output flag, shared by every command and
+	// by error reporting below.
+	format := outputText
+
+	// An unparsable config file fails every command but the ones that
+	// can replace it.
+	cfg, err := loadConfig()
+	var invalidConfig *invalidConfigError
+	if errors.As(err, &invalidConfig) {
+		cfg = &config{}
+	} else if err != nil {
+		reportError(logger, outputText, err)
+		cli.OsExiter(exitCode(err))
+		return
+	}
+
 	app := &cli.App{
-		Name:    "todog",
-		Version: version,
-		Usage:   "Manage your todo list from the command line",
-		Flags:   []cli.Flag{},
+		Name:        "todog",
+		Version:     version,
+		Usage:       "Manage your todo list from the command line",
+		Description: exitCodesHelp,
+		Metadata:    map[string]any{"config": cfg},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Print results as `FORMAT` (text, json, tsv); json and tsv work with list, search, show, add, complete, delete, stats, and timesheet",
+				Value:   outputText,
+			},
+			&cli.StringFlag{
+				Name:    "list",
+				Aliases: []string{"l"},
+				Usage:   "Use the list with this `NAME` instead of the default one",
+			},
+			&cli.StringFlag{
+				Name:  "color",
+				Usage: "Highlight output: `WHEN` is auto, always, or never",
+				Value: "auto",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			var err error
+			if format, err = outputFormat(c); err != nil {
+				return err
+			}
+			if err := checkOutputFormat(c, format); err != nil {
+				return err
+			}
+
+			if invalidConfig != nil {
+				if !replacesConfig(c.Args().Slice()) {
+					return invalidConfig
+				}
+				if err := setAsideConfig(invalidConfig); err != nil {
+					return err
+				}
+			}
+
+			if err := validateColorMode(c.String("color")); err != nil {
+				return err
+			}
+
+			names := []string{c.String("list"), os.Getenv("TODOG_LIST")}
+			// The config command must still work to fix a bad default_list.
+			if c.Args().First() != "config" {
+				names = append(names, cfg.DefaultList)
+			}
+			for _, name := range names {
+				if name == "" {
+					continue
+				}
+				if err := validateListName(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		OnUsageError: onUsageError,
+		// Exit codes are chosen after Run returns, once errors are reported.
+		ExitErrHandler: func(*cli.Context, error) {},
+		Action: func(c *cli.Context) error {
+			if c.NArg() > 0 {
+				return usageErrorf("unknown command %q", c.Args().First())
+			}
+			return cli.ShowAppHelp(c)
+		},
 		Commands: []*cli.Command{
 			{
 				Name:      "list",
 				Usage:     "List all tasks",
-				UsageText: "todog list",
+				UsageText: "todog list [--sort FIELD] [--min-priority LEVEL] [--overdue] [--due-before DATE | --due-within SPAN] [--tag TAG] [--project PROJECT]",
 				Flags: []cli.Flag{
 					&cli.BoolFlag{
 						Name:    "verbose",
 						Usage:   "Enable verbose output",
 						Aliases: []string{"v"},
+						Value:   cfg.List.Verbose,
 					},
 					&cli.BoolFlag{
 						Name:  "hide-completed",
 						Usage: "Hide tasks marked as completed",
+						Value: cfg.List.HideCompleted,
+					},
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "Sort tasks by `FIELD` (priority, created, completed, due)",
+						Value: cfg.List.Sort,
+					},
+					&cli.StringFlag{
+						Name:  "min-priority",
+						Usage: "Only show tasks with at least this priority `LEVEL` (A-Z)",
+					},
+					&cli.BoolFlag{
+						Name:  "overdue",
+						Usage: "Only show open tasks past their due date",
 					},
+					&cli.StringFlag{
+						Name:  "due-before",
+						Usage: "Only show tasks due before `DATE`",
+					},
+					&cli.StringFlag{
+						Name:  "due-within",
+						Usage: "Only show tasks due within `SPAN` from today (e.g. 7d, 2w)",
+					},
+					&cli.BoolFlag{
+						Name:  "archived",
+						Usage: "List archived tasks instead of the current ones",
+					},
+					tagFlag(),
+					projectFlag(),
 				},
 				Action: func(c *cli.Context) error {
-					list, _, err := loadTodoList()
+					archived := c.Bool("archived")
+
+					var list *todo.List
+					var err error
+					if archived {
+						list, err = loadArchive(c)
+					} else {
+						list, _, err = loadTodoList(c)
+					}
 					if err != nil {
 						return err
 					}
 
-					if len(*list) == 0 {
-						fmt.Println("No tasks found.")
+					if len(*list) == 0 && format == outputText {
+						if archived {
+							fmt.Println("No archived tasks.")
+						} else {
+							fmt.Println("No tasks found.")
+						}
 						return nil
 					}
 
 					verbose := c.Bool("verbose")
-					hideCompleted := c.Bool("hide-completed")
+					// Archived tasks are all completed, so a configured
+					// --hide-completed would hide everything.
+					hideCompleted := c.Bool("hide-completed") && !archived
 
-					taskCount := 0
+					now := time.Now()
+					overdue := c.Bool("overdue")
+
+					minPriority, err := todo.ParsePriority(c.String("min-priority"))
+					if err != nil {
+						return usageError(err)
+					}
+
+					if c.IsSet("due-before") && c.IsSet("due-within") {
+						return usageErrorf("--due-before and --due-within cannot be used together")
+					}
+
+					var dueBefore time.Time
+					if s := c.String("due-before"); s != "" {
+						if dueBefore, err = todo.ParseDue(s, now); err != nil {
+							return usageError(err)
+						}
+					}
+
+					if s := c.String("due-within"); s != "" {
+						days, err := todo.ParseDays(s)
+						if err != nil {
+							return usageError(err)
+						}
+						// "Within N days" includes the whole of the last day.
+						dueBefore = todo.StartOfDay(now).AddDate(0, 0, days+1)
+					}
 
+					var entries []listEntry
 					for i, item := range *list {
 						if hideCompleted && item.Done {
 							continue
 						}
+						if minPriority != "" && (item.Priority == "" || item.Priority > minPriority) {
+							continue
+						}
+						if overdue && !item.IsOverdue(now) {
+							continue
+						}
+						if !dueBefore.IsZero() && (!item.HasDue() || !item.Due.Before(dueBefore)) {
+							continue
+						}
+						if !matchesTags(c, item) {
+							continue
+						}
+						entries = append(entries, newListEntry(list, i+1))
+					}
+
+					if err := sortEntries(entries, c.String("sort")); err != nil {
+						return usageError(err)
+					}
+					entries = nestEntries(entries)
+
+					if format != outputText {
+						return writeTasks(os.Stdout, format, "list", entries, nil)
+					}
+
+					taskCount := 0
 
+					for _, entry := range entries {
+						item := entry.Item
 						taskCount++
 
-						status := "[ ]"
-						if item.Done {
-							status = "[x]"
-						}
-						fmt.Printf("%d. %s %s\n", i+1, status, item.Task)
+						fmt.Println(formatEntry(entry, now))
 
 						if verbose {
-							fmt.Printf("    Created:\t%s\n", item.CreatedAt.Format(time.RFC3339))
+							indent := strings.Repeat("  ", entry.Depth)
+							fmt.Printf("%s    Created:\t%s\n", indent, item.CreatedAt.Format(cfg.dateFormat()))
+							if item.Recur != "" {
+								fmt.Printf("%s    Repeats:\t%s\n", indent, item.Recur)
+							}
+							for _, reopened := range item.ReopenedAt {
+								fmt.Printf("%s    Reopened:\t%s\n", indent, reopened.Format(cfg.dateFormat()))
+							}
 							if item.Done {
-								fmt.Printf("    Completed:\t%s\n", item.CompletedAt.Format(time.RFC3339))
+								fmt.Printf("%s    Completed:\t%s\n", indent, item.CompletedAt.Format(cfg.dateFormat()))
+							}
+							if len(item.TimeLog) > 0 {
+								tracked := formatDuration(item.TrackedTime(now))
+								if item.Tracking() {
+									tracked += " (running)"
+								}
+								fmt.Printf("%s    Tracked:\t%s\n", indent, tracked)
 							}
 						}
 					}
@@ -85,23 +284,122 @@ func Execute(version string) {
 				},
 			},
 			{
-				Name:      "add",
-				Usage:     "Add a new task (from args or stdin)",
-				UsageText: "todog add [task description] [--multiline]",
+				Name:      "search",
+				Usage:     "Find tasks whose text matches a pattern",
+				UsageText: "todog search [--regex|--fuzzy] [--hide-completed] [--tag TAG] [--project PROJECT] pattern",
 				Flags: []cli.Flag{
 					&cli.BoolFlag{
-						Name:  "multiline",
-						Usage: "Enable multiline STDIN input (one task per line)",
+						Name:    "regex",
+						Aliases: []string{"r"},
+						Usage:   "Treat the pattern as a regular expression",
+					},
+					&cli.BoolFlag{
+						Name:    "fuzzy",
+						Aliases: []string{"f"},
+						Usage:   "Match the pattern's characters in order, allowing gaps",
+					},
+					&cli.BoolFlag{
+						Name:  "hide-completed",
+						Usage: "Hide completed tasks",
 					},
+					tagFlag(),
+					projectFlag(),
 				},
 				Action: func(c *cli.Context) error {
-					list, file, err := loadTodoList()
+					if c.NArg() == 0 {
+						return usageErrorf("please provide a search pattern")
+					}
+					pattern := strings.Join(c.Args().Slice(), " ")
+
+					var match todo.Matcher
+					var err error
+					switch {
+					case c.Bool("regex") && c.Bool("fuzzy"):
+						return usageErrorf("cannot combine --regex and --fuzzy")
+					case c.Bool("regex"):
+						match, err = todo.RegexMatcher(pattern)
+					case c.Bool("fuzzy"):
+						match, err = todo.FuzzyMatcher(pattern)
+					default:
+						match, err = todo.SubstringMatcher(pattern)
+					}
+					if err != nil {
+						return usageError(err)
+					}
+
+					list, _, err := loadTodoList(c)
 					if err != nil {
 						return err
 					}
 
+					var entries []listEntry
+					var matches [][][]int
+					for i, item := range *list {
+						if c.Bool("hide-completed") && item.Done {
+							continue
+						}
+						if !matchesTags(c, item) {
+							continue
+						}
+						if ranges := match(item.Task); ranges != nil {
+							entries = append(entries, newListEntry(list, i+1))
+							matches = append(matches, ranges)
+						}
+					}
+
+					if format != outputText {
+						return writeTasks(os.Stdout, format, "search", entries, nil)
+					}
+
+					if len(entries) == 0 {
+						fmt.Println("No matching tasks.")
+						return nil
+					}
+
+					now := time.Now()
+					color := colorEnabled(c, os.Stdout)
+					for i, entry := range entries {
+						if color {
+							entry.Item.Task = highlight(entry.Item.Task, matches[i])
+						}
+						fmt.Println(formatEntry(entry, now))
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "add",
+				Usage:     "Add a new task (from args or stdin)",
+				UsageText: "todog add [--priority LEVEL] [--due DATE] [--every RULE] [--parent REF] [--multiline] [task description]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "multiline",
+						Usage: "Enable multiline STDIN input (one task per line)",
+					},
+					&cli.StringFlag{
+						Name:    "priority",
+						Usage:   "Set the task priority `LEVEL` (A-Z, A is highest)",
+						Aliases: []string{"p"},
+					},
+					&cli.StringFlag{
+						Name:  "due",
+						Usage: "Set the due `DATE` (YYYY-MM-DD, today, tomorrow, a weekday, or +Nd)",
+					},
+					&cli.StringFlag{
+						Name:  "every",
+						Usage: "Repeat the task on a `RULE` (daily, weekly, monthly, yearly, or \"every 2 days\")",
+					},
+					&cli.StringFlag{
+						Name:  "parent",
+						Usage: "Add as a subtask of the task with this number or `ID`",
+					},
+				},
+				Action: func(c *cli.Context) error {
 					var tasks []string
+					var err error
 
+					// Read input before taking the lock so a slow stdin
+					// doesn't block other invocations.
 					if c.Bool("multiline") {
 						tasks, err = getTasksMultiline(os.Stdin)
 					} else {
@@ -111,161 +409,1958 @@ func Execute(version string) {
 						return err
 					}
 
-					for _, task := range tasks {
-						list.Add(task)
-						fmt.Printf("Added task: %q\n", task)
+					priority, err := todo.ParsePriority(c.String("priority"))
+					if err != nil {
+						return usageError(err)
+					}
+
+					var due time.Time
+					if s := c.String("due"); s != "" {
+						if due, err = todo.ParseDue(s, time.Now()); err != nil {
+							return usageError(err)
+						}
+					}
+
+					recur, err := todo.ParseRecurrence(c.String("every"))
+					if err != nil {
+						return usageError(err)
+					}
+
+					var added []listEntry
+					err = updateTodoList(c, func(list *todo.List) error {
+						parent := 0
+						if ref := c.String("parent"); ref != "" {
+							var err error
+							if parent, err = list.Resolve(ref); err != nil {
+								return fmt.Errorf("failed to find parent task: %w", err)
+							}
+						}
+
+						for _, task := range tasks {
+							list.Add(task)
+							if err := list.Prioritize(len(*list), priority); err != nil {
+								return err
+							}
+							if err := list.SetDue(len(*list), due); err != nil {
+								return err
+							}
+							if err := list.SetRecurrence(len(*list), recur); err != nil {
+								return err
+							}
+							if err := list.SetParent(len(*list), parent); err != nil {
+								return err
+							}
+							added = append(added, newListEntry(list, len(*list)))
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					if format != outputText {
+						return writeTasks(os.Stdout, format, "add", added, nil)
 					}
 
-					if err := list.Save(file); err != nil {
-						return fmt.Errorf("failed to save tasks: %w", err)
+					for _, entry := range added {
+						fmt.Printf("Added task: %q [%s]\n", entry.Item.Task, entry.Item.ID)
 					}
 
 					return nil
 				},
 			},
 			{
-				Name:      "complete",
-				Usage:     "Mark a task as complete",
-				UsageText: "todog complete <task number>",
+				Name:      "agenda",
+				Usage:     "Show open tasks grouped by due date",
+				UsageText: "todog agenda [--tag TAG] [--project PROJECT]",
+				Flags: []cli.Flag{
+					tagFlag(),
+					projectFlag(),
+				},
 				Action: func(c *cli.Context) error {
-					if c.NArg() != 1 {
-						return fmt.Errorf("please provide a task number to complete")
+					list, _, err := loadTodoList(c)
+					if err != nil {
+						return err
+					}
+
+					now := time.Now()
+					groups := groupAgenda(list, now, func(item todo.Item) bool {
+						return matchesTags(c, item)
+					})
+
+					printed := false
+					for _, bucket := range agendaBuckets {
+						entries := groups[bucket]
+						if len(entries) == 0 {
+							continue
+						}
+
+						if printed {
+							fmt.Println()
+						}
+						printed = true
+
+						fmt.Printf("%s:\n", bucket)
+						for _, entry := range entries {
+							fmt.Printf("  %s\n", formatEntry(entry, now))
+						}
 					}
 
-					num, err := strconv.Atoi(c.Args().First())
-					if err != nil || num <= 0 {
-						return fmt.Errorf("invalid task number: %s", c.Args().First())
+					if !printed {
+						fmt.Println("No open tasks.")
 					}
 
-					list, file, err := loadTodoList()
+					return nil
+				},
+			},
+			{
+				Name:      "tags",
+				Usage:     "List projects and contexts with task counts",
+				UsageText: "todog tags",
+				Action: func(c *cli.Context) error {
+					list, _, err := loadTodoList(c)
 					if err != nil {
 						return err
 					}
 
-					if err := list.Complete(num); err != nil {
-						return fmt.Errorf("failed to complete task: %w", err)
+					counts := list.TagCounts()
+					if len(counts) == 0 {
+						fmt.Println("No tags found.")
+						return nil
 					}
 
-					if err := list.Save(file); err != nil {
-						return fmt.Errorf("failed to save list: %w", err)
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					for _, tc := range counts {
+						fmt.Fprintf(w, "%s\t%d open\t%d done\n", tc.Tag, tc.Open, tc.Done)
 					}
-
-					fmt.Printf("Marked task #%d as completed.\n", num)
-					return nil
+					return w.Flush()
 				},
 			},
 			{
-				Name:      "delete",
-				Usage:     "Delete a task by its number",
-				UsageText: "todog delete <task number>",
+				Name:      "ui",
+				Usage:     "Browse and edit tasks in a full-screen interface",
+				UsageText: "todog ui",
 				Action: func(c *cli.Context) error {
-					if c.NArg() != 1 {
-						return fmt.Errorf("please provide a task number to delete")
+					if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+						return usageErrorf("ui needs an interactive terminal")
 					}
 
-					num, err := strconv.Atoi(c.Args().First())
-					if err != nil || num <= 0 {
-						return fmt.Errorf("invalid task number: %s", c.Args().First())
+					m, err := tui.NewModel(listStore{c})
+					if err != nil {
+						return err
 					}
 
-					list, file, err := loadTodoList()
+					term, err := tui.OpenTTY(os.Stdin, os.Stdout)
 					if err != nil {
-						return err
+						return fmt.Errorf("failed to start ui: %w", err)
 					}
+					defer term.Close()
 
-					if err := list.Delete(num); err != nil {
-						return fmt.Errorf("failed to delete task: %w", err)
+					return tui.Run(term, m)
+				},
+			},
+			{
+				Name:      "serve",
+				Usage:     "Serve the list over a local HTTP/JSON API",
+				UsageText: "todog serve [--addr HOST:PORT]",
+				Description: "Endpoints: GET and POST /tasks, and GET, PATCH, and DELETE\n" +
+					"/tasks/{number|ID}. Changes take the same file lock as other commands\n" +
+					"and can be undone with todog undo. There is no authentication, so keep\n" +
+					"the server on a loopback address; it only answers requests addressed to\n" +
+					"localhost, 127.0.0.1, or [::1] that don't come from another web site.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "addr",
+						Usage: "Listen on `HOST:PORT`",
+						Value: "127.0.0.1:8080",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 0 {
+						return usageErrorf("serve takes no arguments")
 					}
 
-					if err := list.Save(file); err != nil {
-						return fmt.Errorf("failed to save list: %w", err)
+					addr := c.String("addr")
+					file := getTodoFileName(c)
+
+					ln, err := net.Listen("tcp", addr)
+					if err != nil {
+						return fmt.Errorf("failed to listen on %s: %w", addr, err)
 					}
 
-					fmt.Printf("Deleted task #%d.\n", num)
-					return nil
-				},
-			},
-		},
-	}
+					srv := &http.Server{
+						Handler:           server.New(fileStore{file}, ln.Addr().(*net.TCPAddr).Port),
+						ReadHeaderTimeout: 10 * time.Second,
+					}
 
-	if err := app.Run(os.Args); err != nil {
-		logger.Printf("Error: %v", err)
-		cli.OsExiter(1)
-	}
-}
+					ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+					defer stop()
 
-func getTask(r io.Reader, args ...string) ([]string, error) {
-	if len(args) > 0 {
-		return []string{strings.Join(args, " ")}, nil
-	}
+					go func() {
+						<-ctx.Done()
+						shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+						defer cancel()
+						_ = srv.Shutdown(shutdownCtx)
+					}()
 
-	scanner := bufio.NewScanner(r)
-	if !scanner.Scan() {
-		if err := scanner.Err(); err != nil {
-			return nil, err
-		}
-		return nil, fmt.Errorf("task input is required")
-	}
+					if !isLoopback(ln.Addr()) {
+						logger.Printf("Warning: %s is reachable from other machines and the API has no authentication.", ln.Addr())
+					}
+					fmt.Printf("Serving %s on http://%s (press Ctrl-C to stop).\n", file, ln.Addr())
 
-	line := strings.TrimSpace(scanner.Text())
-	if line == "" {
-		return nil, fmt.Errorf("task cannot be blank")
-	}
+					if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+						return fmt.Errorf("server failed: %w", err)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "Show everything about a task, including notes and attachments",
+				UsageText: "todog show <task number|ID>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return usageErrorf("please provide a task number or ID to show")
+					}
 
-	return []string{line}, nil
-}
+					list, _, err := loadTodoList(c)
+					if err != nil {
+						return err
+					}
 
-func getTasksMultiline(r io.Reader) ([]string, error) {
-	scanner := bufio.NewScanner(r)
-	var tasks []string
+					num, err := list.Resolve(c.Args().First())
+					if err != nil {
+						return fmt.Errorf("failed to show task: %w", err)
+					}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			tasks = append(tasks, line)
-		}
-	}
+					if format != outputText {
+						return writeTasks(os.Stdout, format, "show", []listEntry{newListEntry(list, num)}, nil)
+					}
+					return writeTaskDetails(os.Stdout, list, num, time.Now(), configFrom(c))
+				},
+			},
+			{
+				Name:      "note",
+				Usage:     "Add a timestamped note to a task",
+				UsageText: "todog note <task number|ID> <text...>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() < 2 {
+						return usageErrorf("please provide a task number or ID and the note text")
+					}
+					text := strings.Join(c.Args().Tail(), " ")
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
+					var num int
+					var id string
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, err = list.Resolve(c.Args().First()); err != nil {
+							return fmt.Errorf("failed to add note: %w", err)
+						}
+						id = (*list)[num-1].ID
 
-	if len(tasks) == 0 {
-		return nil, fmt.Errorf("no task input provided")
-	}
+						if err := list.AddNote(num, text, time.Now()); err != nil {
+							return fmt.Errorf("failed to add note: %w", err)
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
 
-	return tasks, nil
-}
+					fmt.Printf("Added note to task #%d [%s].\n", num, id)
+					return nil
+				},
+			},
+			{
+				Name:      "attach",
+				Usage:     "Link files or URLs to a task",
+				UsageText: "todog attach <task number|ID> <path|URL...>",
+				Description: "File paths are stored as absolute paths. They don't have to exist\n" +
+					"yet; show marks the ones that are missing.",
+				Action: func(c *cli.Context) error {
+					if c.NArg() < 2 {
+						return usageErrorf("please provide a task number or ID and a file path or URL")
+					}
 
-func loadTodoList() (*todo.List, string, error) {
-	file := getTodoFileName()
-	list := &todo.List{}
-	if err := list.Get(file); err != nil {
-		return nil, "", fmt.Errorf("failed to load tasks: %w", err)
-	}
-	return list, file, nil
-}
+					var num int
+					var id string
+					var refs []string
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, err = list.Resolve(c.Args().First()); err != nil {
+							return fmt.Errorf("failed to attach: %w", err)
+						}
+						id = (*list)[num-1].ID
 
-func getTodoFileName() string {
-	if path := os.Getenv("TODOG_FILE"); path != "" {
-		return path
-	}
+						for _, arg := range c.Args().Tail() {
+							ref := attachmentRef(arg)
+							if err := list.Attach(num, ref); err != nil {
+								return fmt.Errorf("failed to attach: %w", err)
+							}
+							refs = append(refs, ref)
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
 
-	switch os.Getenv("TODOG_ENV") {
-	case "development":
-		tmpPath := filepath.Join(".", "tmp")
-		_ = os.MkdirAll(tmpPath, 0755)
-		return filepath.Join(tmpPath, "todo.json")
-	case "test":
-		fmt.Fprintln(os.Stderr, "ERROR: TODOG_FILE must be set in test environment.")
-		os.Exit(1)
-	}
+					for _, ref := range refs {
+						fmt.Printf("Attached %s to task #%d [%s].\n", ref, num, id)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "detach",
+				Usage:     "Remove a file or URL from a task",
+				UsageText: "todog detach <task number|ID> <path|URL>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return usageErrorf("please provide a task number or ID and the attachment to remove")
+					}
 
-	if home, err := os.UserHomeDir(); err == nil {
-		configPath := filepath.Join(home, ".todog")
-		_ = os.MkdirAll(configPath, 0755)
-		return filepath.Join(configPath, "todo.json")
+					var num int
+					var id, ref string
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, err = list.Resolve(c.Args().First()); err != nil {
+							return fmt.Errorf("failed to detach: %w", err)
+						}
+						id = (*list)[num-1].ID
+
+						// Accept a path as stored or relative to here.
+						ref = c.Args().Get(1)
+						if !slices.Contains((*list)[num-1].Attachments, ref) {
+							ref = attachmentRef(ref)
+						}
+						if err := list.Detach(num, ref); err != nil {
+							return fmt.Errorf("failed to detach: %w", err)
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Removed %s from task #%d [%s].\n", ref, num, id)
+					return nil
+				},
+			},
+			{
+				Name:      "complete",
+				Usage:     "Mark tasks as complete",
+				UsageText: "todog complete [--cascade] [--tag TAG] [--project PROJECT] [task number|ID|range...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "cascade",
+						Usage: "Also complete every open subtask",
+					},
+					tagFlag(),
+					projectFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					var completed, spawned []listEntry
+					err := updateTodoList(c, func(list *todo.List) error {
+						nums, err := selectTasks(c, list, func(item todo.Item) bool {
+							return !item.Done
+						})
+						if err != nil {
+							return fmt.Errorf("failed to complete task: %w", err)
+						}
+
+						if c.Bool("cascade") {
+							nums = withOpenDescendants(list, nums)
+						}
+
+						for _, num := range nums {
+							size := len(*list)
+							if err := list.Complete(num); err != nil {
+								return fmt.Errorf("failed to complete task: %w", err)
+							}
+							completed = append(completed, listEntry{Num: num, Item: (*list)[num-1]})
+
+							// Recurring tasks append their next occurrence.
+							for n := size + 1; n <= len(*list); n++ {
+								spawned = append(spawned, listEntry{Num: n, Item: (*list)[n-1]})
+							}
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					if format != outputText {
+						return writeTasks(os.Stdout, format, "complete", completed, spawned)
+					}
+
+					for _, entry := range completed {
+						fmt.Printf("Marked task #%d [%s] as completed.\n", entry.Num, entry.Item.ID)
+					}
+					for _, entry := range spawned {
+						fmt.Printf("Scheduled next occurrence as task #%d [%s], due %s.\n",
+							entry.Num, entry.Item.ID, entry.Item.Due.Format(todo.DateLayout))
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "reopen",
+				Usage:     "Mark a completed task as not done",
+				UsageText: "todog reopen <task number|ID>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return usageErrorf("please provide a task number or ID to reopen")
+					}
+
+					var num int
+					var id string
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, err = list.Resolve(c.Args().First()); err != nil {
+							return fmt.Errorf("failed to reopen task: %w", err)
+						}
+						id = (*list)[num-1].ID
+
+						if err := list.Reopen(num); err != nil {
+							return fmt.Errorf("failed to reopen task: %w", err)
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Reopened task #%d [%s].\n", num, id)
+					return nil
+				},
+			},
+			{
+				Name:      "toggle",
+				Usage:     "Complete an open task or reopen a completed one",
+				UsageText: "todog toggle <task number|ID>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return usageErrorf("please provide a task number or ID to toggle")
+					}
+
+					var num int
+					var item todo.Item
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, err = list.Resolve(c.Args().First()); err != nil {
+							return fmt.Errorf("failed to toggle task: %w", err)
+						}
+
+						if err := list.Toggle(num); err != nil {
+							return fmt.Errorf("failed to toggle task: %w", err)
+						}
+						item = (*list)[num-1]
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					if item.Done {
+						fmt.Printf("Marked task #%d [%s] as completed.\n", num, item.ID)
+					} else {
+						fmt.Printf("Reopened task #%d [%s].\n", num, item.ID)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "prioritize",
+				Usage:     "Set or clear the priority of a task",
+				UsageText: "todog prioritize <task number|ID> <level|none>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return usageErrorf("please provide a task number or ID and a priority level")
+					}
+
+					var num int
+					var item todo.Item
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, err = list.Resolve(c.Args().First()); err != nil {
+							return fmt.Errorf("failed to prioritize task: %w", err)
+						}
+						if err := list.Prioritize(num, c.Args().Get(1)); err != nil {
+							return fmt.Errorf("failed to prioritize task: %w", err)
+						}
+						item = (*list)[num-1]
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					id := item.ID
+					if p := item.Priority; p != "" {
+						fmt.Printf("Set priority of task #%d [%s] to %s.\n", num, id, p)
+					} else {
+						fmt.Printf("Cleared priority of task #%d [%s].\n", num, id)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "edit",
+				Usage:     "Change the text, priority, or due date of a task",
+				UsageText: "todog edit [--editor] [--priority LEVEL] [--due DATE] [--every RULE] [--parent REF] [task number|ID] [new text]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "editor",
+						Usage:   "Edit in $EDITOR (the whole list when no task is given)",
+						Aliases: []string{"e"},
+					},
+					&cli.StringFlag{
+						Name:    "priority",
+						Usage:   "Set the task priority `LEVEL` (A-Z, or none to clear)",
+						Aliases: []string{"p"},
+					},
+					&cli.StringFlag{
+						Name:  "due",
+						Usage: "Set the due `DATE` (or none to clear)",
+					},
+					&cli.StringFlag{
+						Name:  "every",
+						Usage: "Set the recurrence `RULE` (or none to stop repeating)",
+					},
+					&cli.StringFlag{
+						Name:  "parent",
+						Usage: "Move under the task with this number or `ID` (or none for top level)",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Bool("editor") && c.NArg() == 0 {
+						return editWholeList(c)
+					}
+
+					if c.NArg() == 0 {
+						return usageErrorf("please provide a task number or ID to edit")
+					}
+
+					list, _, err := loadTodoList(c)
+					if err != nil {
+						return err
+					}
+
+					num, err := list.Resolve(c.Args().First())
+					if err != nil {
+						return fmt.Errorf("failed to edit task: %w", err)
+					}
+					id := (*list)[num-1].ID
+
+					// Work out the new text before taking the lock, since the
+					// editor may stay open for a while.
+					var text string
+					hasText := true
+					switch {
+					case c.Bool("editor"):
+						edited, err := editText((*list)[num-1].Task + "\n")
+						if err != nil {
+							return err
+						}
+						tasks, err := getTask(strings.NewReader(edited))
+						if err != nil {
+							return err
+						}
+						text = tasks[0]
+					case c.NArg() > 1:
+						text = strings.Join(c.Args().Tail(), " ")
+					case !c.IsSet("priority") && !c.IsSet("due") && !c.IsSet("every") && !c.IsSet("parent"):
+						return usageErrorf("please provide new text, --priority, --due, --every, --parent, or --editor")
+					default:
+						hasText = false
+					}
+
+					var due time.Time
+					if s := c.String("due"); c.IsSet("due") && s != "none" {
+						if due, err = todo.ParseDue(s, time.Now()); err != nil {
+							return usageError(err)
+						}
+					}
+
+					var edited todo.Item
+					err = updateTodoList(c, func(list *todo.List) error {
+						num, err := list.Resolve(id)
+						if err != nil {
+							return fmt.Errorf("failed to edit task: %w", err)
+						}
+
+						if hasText {
+							if err := list.Edit(num, text); err != nil {
+								return fmt.Errorf("failed to edit task: %w", err)
+							}
+						}
+						if c.IsSet("priority") {
+							if err := list.Prioritize(num, c.String("priority")); err != nil {
+								return fmt.Errorf("failed to edit task: %w", err)
+							}
+						}
+						if c.IsSet("due") {
+							if err := list.SetDue(num, due); err != nil {
+								return fmt.Errorf("failed to edit task: %w", err)
+							}
+						}
+						if c.IsSet("every") {
+							if err := list.SetRecurrence(num, c.String("every")); err != nil {
+								return fmt.Errorf("failed to edit task: %w", err)
+							}
+						}
+						if ref := c.String("parent"); c.IsSet("parent") {
+							parent := 0
+							if ref != "none" {
+								if parent, err = list.Resolve(ref); err != nil {
+									return fmt.Errorf("failed to find parent task: %w", err)
+								}
+							}
+							if err := list.SetParent(num, parent); err != nil {
+								return fmt.Errorf("failed to edit task: %w", err)
+							}
+						}
+
+						edited = (*list)[num-1]
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Updated task [%s]: %q\n", edited.ID, edited.Task)
+					return nil
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete tasks by number, ID, or selector",
+				UsageText: "todog delete [--done] [--tag TAG] [--project PROJECT] [task number|ID|range...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "done",
+						Usage: "Select all completed tasks",
+					},
+					tagFlag(),
+					projectFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					var deleted []listEntry
+					err := updateTodoList(c, func(list *todo.List) error {
+						nums, err := selectTasks(c, list, func(item todo.Item) bool {
+							return item.Done || !c.Bool("done")
+						})
+						if err != nil {
+							return fmt.Errorf("failed to delete task: %w", err)
+						}
+
+						for _, num := range nums {
+							deleted = append(deleted, listEntry{Num: num, Item: (*list)[num-1]})
+						}
+
+						// Delete by ID, since each deletion shifts later positions.
+						for _, entry := range deleted {
+							num, err := list.Resolve(entry.Item.ID)
+							if err != nil {
+								return fmt.Errorf("failed to delete task: %w", err)
+							}
+							if err := list.Delete(num); err != nil {
+								return fmt.Errorf("failed to delete task: %w", err)
+							}
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					if format != outputText {
+						return writeTasks(os.Stdout, format, "delete", deleted, nil)
+					}
+
+					for _, entry := range deleted {
+						fmt.Printf("Deleted task #%d [%s].\n", entry.Num, entry.Item.ID)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "move",
+				Usage: "Reorder a task, or move tasks with their subtasks to another list",
+				UsageText: "todog move <task number|ID> <position>\n" +
+					"   todog move [task number|ID|range...] --to LIST [--tag TAG] [--project PROJECT]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "to",
+						Usage: "Move the tasks to the list with this `NAME`",
+					},
+					tagFlag(),
+					projectFlag(),
+				},
+				Action: func(c *cli.Context) error {
+					to := c.String("to")
+					if to == "" {
+						if c.IsSet("tag") || c.IsSet("project") {
+							return usageErrorf("--tag and --project select tasks to move to another list; please add --to LIST")
+						}
+						if c.NArg() != 2 {
+							return usageErrorf("please provide a task number or ID and its new position")
+						}
+
+						position, err := strconv.Atoi(c.Args().Get(1))
+						if err != nil {
+							return usageErrorf("invalid position %q", c.Args().Get(1))
+						}
+						return reorderTask(c, c.Args().First(), func(*todo.List) int { return position })
+					}
+					if err := validateListName(to); err != nil {
+						return err
+					}
+
+					moved, added, err := moveToList(c, to)
+					if err != nil {
+						return err
+					}
+
+					for i, entry := range moved {
+						fmt.Printf("Moved task #%d [%s] to %s as #%d [%s].\n",
+							entry.Num, entry.Item.ID, to, added[i].Num, added[i].Item.ID)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "top",
+				Usage:     "Move a task to the top of the list",
+				UsageText: "todog top <task number|ID>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return usageErrorf("please provide a task number or ID to move")
+					}
+					return reorderTask(c, c.Args().First(), func(list *todo.List) int { return 1 })
+				},
+			},
+			{
+				Name:      "bottom",
+				Usage:     "Move a task to the bottom of the list",
+				UsageText: "todog bottom <task number|ID>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return usageErrorf("please provide a task number or ID to move")
+					}
+					return reorderTask(c, c.Args().First(), func(list *todo.List) int { return len(*list) })
+				},
+			},
+			{
+				Name:      "sort",
+				Usage:     "Permanently reorder the list",
+				UsageText: "todog sort --by created|text|done",
+				Description: "Unlike list --sort, this rewrites the stored order, so task numbers\n" +
+					"change. Ties keep their current order.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "by",
+						Usage: "Sort by `FIELD`: created (oldest first), text, or done (open first)",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					by := c.String("by")
+					if by == "" {
+						return usageErrorf("please provide the field to sort by with --by")
+					}
+					if err := (&todo.List{}).Sort(by); err != nil {
+						return usageError(err)
+					}
+
+					var count int
+					err := updateTodoList(c, func(list *todo.List) error {
+						count = len(*list)
+						return list.Sort(by)
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Sorted %s by %s.\n", plural(count, "task"), by)
+					return nil
+				},
+			},
+			{
+				Name:      "lists",
+				Usage:     "Show the named lists, marking the current one",
+				UsageText: "todog lists [--set-default NAME]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "set-default",
+						Usage: "Use the list with this `NAME` when --list and TODOG_LIST aren't given",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if name := c.String("set-default"); name != "" {
+						if err := validateListName(name); err != nil {
+							return err
+						}
+						if err := setDefaultList(c, name); err != nil {
+							return err
+						}
+
+						fmt.Printf("Default list is now %s.\n", name)
+						return nil
+					}
+
+					names, err := listNames()
+					if err != nil {
+						return storageErrorf("failed to find lists: %w", err)
+					}
+
+					current := resolveTodoFile(c).List
+					if current != "" && !slices.Contains(names, current) {
+						names = append(names, current)
+						slices.Sort(names)
+					}
+
+					for _, name := range names {
+						list := &todo.List{}
+						if err := list.Load(listFileName(name)); err != nil {
+							return storageErrorf("failed to load list %s: %w", name, err)
+						}
+
+						open := 0
+						for _, item := range *list {
+							if !item.Done {
+								open++
+							}
+						}
+
+						marker := " "
+						if name == current {
+							marker = "*"
+						}
+						fmt.Printf("%s %s (%d open)\n", marker, name, open)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "init",
+				Usage:     "Create a todo list for the current directory and its subdirectories",
+				UsageText: "todog init",
+				Action: func(c *cli.Context) error {
+					file, err := filepath.Abs(projectFileName)
+					if err != nil {
+						return err
+					}
+
+					if _, err := os.Stat(file); err == nil {
+						return fmt.Errorf("%s already exists", file)
+					}
+
+					if err := (&todo.List{}).Save(file); err != nil {
+						return storageErrorf("failed to create list: %w", err)
+					}
+
+					fmt.Printf("Created %s.\n", file)
+					return nil
+				},
+			},
+			{
+				Name:      "where",
+				Usage:     "Show which todo file is in use and why",
+				UsageText: "todog where",
+				Action: func(c *cli.Context) error {
+					source := resolveTodoFile(c)
+
+					file, err := filepath.Abs(source.File)
+					if err != nil {
+						file = source.File
+					}
+
+					fmt.Println(file)
+					fmt.Printf("(%s)\n", source.Reason)
+					return nil
+				},
+			},
+			{
+				Name:      "config",
+				Usage:     "Read and change settings in the config file",
+				UsageText: "todog config get KEY | set KEY VALUE | unset KEY | list",
+				Description: "Settings apply unless a flag or environment variable overrides them.\n\nKeys:\n" +
+					configKeysHelp(),
+				Subcommands: []*cli.Command{
+					{
+						Name:      "get",
+						Usage:     "Print the value of a setting",
+						UsageText: "todog config get KEY",
+						Action: func(c *cli.Context) error {
+							if c.NArg() != 1 {
+								return usageErrorf("please provide a config key")
+							}
+
+							value, err := getConfigValue(cfg, c.Args().First())
+							if err != nil {
+								return err
+							}
+							if value == "" {
+								return fmt.Errorf("%s is not set", c.Args().First())
+							}
+
+							fmt.Println(value)
+							return nil
+						},
+					},
+					{
+						Name:      "set",
+						Usage:     "Change a setting",
+						UsageText: "todog config set KEY VALUE",
+						Action: func(c *cli.Context) error {
+							if c.NArg() < 2 {
+								return usageErrorf("please provide a config key and value")
+							}
+
+							key := c.Args().First()
+							value := strings.Join(c.Args().Tail(), " ")
+							if err := setConfigValue(c, cfg, key, value); err != nil {
+								return err
+							}
+							if err := saveConfig(cfg); err != nil {
+								return err
+							}
+
+							fmt.Printf("Set %s to %q.\n", key, value)
+							return nil
+						},
+					},
+					{
+						Name:      "unset",
+						Usage:     "Remove a setting",
+						UsageText: "todog config unset KEY",
+						Action: func(c *cli.Context) error {
+							if c.NArg() != 1 {
+								return usageErrorf("please provide a config key")
+							}
+
+							key := c.Args().First()
+							if err := setConfigValue(c, cfg, key, ""); err != nil {
+								return err
+							}
+							if err := saveConfig(cfg); err != nil {
+								return err
+							}
+
+							fmt.Printf("Unset %s.\n", key)
+							return nil
+						},
+					},
+					{
+						Name:      "list",
+						Usage:     "Print every setting that is set",
+						UsageText: "todog config list",
+						Action: func(c *cli.Context) error {
+							printed := false
+							for _, key := range configKeys {
+								if value := key.get(cfg); value != "" {
+									fmt.Printf("%s=%s\n", key.name, value)
+									printed = true
+								}
+							}
+
+							aliases := slices.Sorted(maps.Keys(cfg.Aliases))
+							for _, alias := range aliases {
+								fmt.Printf("%s%s=%s\n", aliasPrefix, alias, cfg.Aliases[alias])
+								printed = true
+							}
+
+							if !printed {
+								fmt.Println("No settings configured.")
+							}
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:      "archive",
+				Usage:     "Move completed tasks to the archive file",
+				UsageText: "todog archive [--older-than SPAN]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "older-than",
+						Usage: "Only archive tasks completed more than `SPAN` ago (e.g. 30d, 2w)",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					var cutoff time.Time
+					if s := c.String("older-than"); s != "" {
+						days, err := todo.ParseDays(s)
+						if err != nil {
+							return usageError(err)
+						}
+						cutoff = time.Now().AddDate(0, 0, -days)
+					}
+
+					archiveFile := todo.ArchiveFile(getTodoFileName(c))
+
+					// Undoing would bring the tasks back while leaving them
+					// in the archive, so archiving is final.
+					var archived []todo.Item
+					err := updateTodoListFinal(c, func(list *todo.List) error {
+						archived = list.Archive(cutoff)
+
+						// Write the archive first: if saving the list then
+						// fails, tasks are duplicated rather than lost.
+						if err := todo.AppendArchive(archiveFile, archived); err != nil {
+							return storageErrorf("failed to write archive: %w", err)
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					if len(archived) == 0 {
+						fmt.Println("No completed tasks to archive.")
+						return nil
+					}
+
+					fmt.Printf("Archived %s to %s.\n", plural(len(archived), "task"), archiveFile)
+					return nil
+				},
+			},
+			{
+				Name:      "stats",
+				Usage:     "Report created and completed tasks over time",
+				UsageText: "todog stats [--by day|week] [--last N]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "by",
+						Usage: "Count activity per `PERIOD` (day or week)",
+						Value: string(todo.Week),
+					},
+					&cli.IntFlag{
+						Name:  "last",
+						Usage: "Report the last `N` periods",
+						Value: 8,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					period, err := todo.ParsePeriod(c.String("by"))
+					if err != nil {
+						return usageError(err)
+					}
+					if c.Int("last") <= 0 {
+						return usageErrorf("--last must be a positive number")
+					}
+
+					list, _, err := loadTodoList(c)
+					if err != nil {
+						return err
+					}
+
+					archive, err := loadArchive(c)
+					if err != nil {
+						return err
+					}
+
+					stats := todo.ComputeStats(*list, *archive, time.Now(), period, c.Int("last"))
+					return writeStats(os.Stdout, format, stats)
+				},
+			},
+			{
+				Name:      "start",
+				Usage:     "Start tracking time on a task",
+				UsageText: "todog start <task number|ID>",
+				Description: "Only one task is tracked at a time: starting another stops the\n" +
+					"running timer first. Completing a task stops its timer.",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return usageErrorf("please provide a task number or ID to start")
+					}
+
+					now := time.Now()
+					var num, stopped int
+					var item, stoppedItem todo.Item
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, err = list.Resolve(c.Args().First()); err != nil {
+							return fmt.Errorf("failed to start timer: %w", err)
+						}
+
+						if stopped, err = list.StartTimer(num, now); err != nil {
+							return fmt.Errorf("failed to start timer: %w", err)
+						}
+						item = (*list)[num-1]
+						if stopped != 0 {
+							stoppedItem = (*list)[stopped-1]
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					if stopped != 0 {
+						last := stoppedItem.TimeLog[len(stoppedItem.TimeLog)-1]
+						fmt.Printf("Stopped tracking task #%d [%s] after %s.\n",
+							stopped, stoppedItem.ID, formatDuration(last.Duration(now)))
+					}
+					fmt.Printf("Started tracking task #%d [%s].\n", num, item.ID)
+					return nil
+				},
+			},
+			{
+				Name:      "stop",
+				Usage:     "Stop the running timer",
+				UsageText: "todog stop",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 0 {
+						return usageErrorf("stop takes no arguments")
+					}
+
+					now := time.Now()
+					var num int
+					var id string
+					var interval todo.Interval
+					err := updateTodoList(c, func(list *todo.List) error {
+						var err error
+						if num, interval, err = list.StopTimer(now); err != nil {
+							return fmt.Errorf("failed to stop timer: %w", err)
+						}
+						id = (*list)[num-1].ID
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Stopped tracking task #%d [%s] after %s.\n", num, id, formatDuration(interval.Duration(now)))
+					return nil
+				},
+			},
+			{
+				Name:      "timesheet",
+				Usage:     "Report tracked hours per task and tag",
+				UsageText: "todog timesheet [--week | --since DATE]",
+				Description: "Without flags, the report covers all tracked time. Archived tasks\n" +
+					"are included.",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "week",
+						Usage: "Only count time tracked since Monday",
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Only count time tracked on or after `DATE`",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					now := time.Now()
+
+					var from time.Time
+					switch {
+					case c.Bool("week") && c.IsSet("since"):
+						return usageErrorf("--week and --since cannot be used together")
+					case c.Bool("week"):
+						from = todo.StartOfWeek(now)
+					case c.IsSet("since"):
+						date, err := todo.ParseDue(c.String("since"), now)
+						if err != nil {
+							return usageError(err)
+						}
+						from = todo.StartOfDay(date)
+					}
+
+					list, _, err := loadTodoList(c)
+					if err != nil {
+						return err
+					}
+
+					archive, err := loadArchive(c)
+					if err != nil {
+						return err
+					}
+
+					items := append(append([]todo.Item(nil), *list...), *archive...)
+					sheet := timesheet{From: from, To: now}
+					sheet.Tasks, sheet.Tags = todo.Timesheet(items, from, now, now)
+					return writeTimesheet(os.Stdout, format, sheet)
+				},
+			},
+			{
+				Name:      "export",
+				Usage:     "Write all tasks to stdout in another format",
+				UsageText: "todog export [--format todotxt|csv|markdown|json]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output `FORMAT` (todotxt, csv, markdown, json)",
+						Value: "todotxt",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					list, _, err := loadTodoList(c)
+					if err != nil {
+						return err
+					}
+
+					switch c.String("format") {
+					case "todotxt":
+						return todo.WriteTodoTxt(os.Stdout, *list)
+					case "csv":
+						return todo.WriteCSV(os.Stdout, *list)
+					case "markdown", "md":
+						return todo.WriteMarkdown(os.Stdout, *list)
+					case "json":
+						return todo.WriteJSON(os.Stdout, *list)
+					default:
+						return usageErrorf("unsupported export format %q", c.String("format"))
+					}
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Append tasks from a file (or - for stdin)",
+				UsageText: "todog import [--format todotxt|markdown] <file|->",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Input `FORMAT` (todotxt, markdown)",
+						Value: "todotxt",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return usageErrorf("please provide a file to import (or - for stdin)")
+					}
+
+					input, err := openInput(c.Args().First())
+					if err != nil {
+						return err
+					}
+					defer input.Close()
+
+					var items []todo.Item
+					switch c.String("format") {
+					case "todotxt":
+						items, err = todo.ParseTodoTxt(input)
+					case "markdown", "md":
+						items, err = getChecklistItems(input)
+					default:
+						return usageErrorf("unsupported import format %q", c.String("format"))
+					}
+					if err != nil {
+						return fmt.Errorf("failed to import tasks: %w", err)
+					}
+
+					var imported []todo.Item
+					err = updateTodoList(c, func(list *todo.List) error {
+						imported = list.Import(items)
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Imported %d tasks.\n", len(imported))
+					return nil
+				},
+			},
+			{
+				Name:      "undo",
+				Usage:     "Revert the last change to the list",
+				UsageText: "todog undo",
+				Action: func(c *cli.Context) error {
+					command, err := replayJournal(c, (*todo.Journal).Undo)
+					if err != nil {
+						return fmt.Errorf("failed to undo: %w", err)
+					}
+
+					fmt.Printf("Undid: %s\n", command)
+					return nil
+				},
+			},
+			{
+				Name:      "redo",
+				Usage:     "Reapply the last undone change",
+				UsageText: "todog redo",
+				Action: func(c *cli.Context) error {
+					command, err := replayJournal(c, (*todo.Journal).Redo)
+					if err != nil {
+						return fmt.Errorf("failed to redo: %w", err)
+					}
+
+					fmt.Printf("Redid: %s\n", command)
+					return nil
+				},
+			},
+			{
+				Name:      "history",
+				Usage:     "Show recent changes that can be undone",
+				UsageText: "todog history [--limit N]",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Show at most `N` operations",
+						Value: 10,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					journal := &todo.Journal{}
+					if err := journal.Get(todo.JournalFile(getTodoFileName(c))); err != nil {
+						return storageErrorf("failed to load history: %w", err)
+					}
+
+					if len(journal.Operations) == 0 {
+						fmt.Println("No history yet.")
+						return nil
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					shown := 0
+					for i := len(journal.Operations) - 1; i >= 0 && shown < c.Int("limit"); i-- {
+						op := journal.Operations[i]
+
+						status := ""
+						if i >= len(journal.Operations)-journal.Undone {
+							status = "(undone)"
+						}
+
+						fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, op.Time.Format(time.RFC3339), op.Command, status)
+						shown++
+					}
+					return w.Flush()
+				},
+			},
+		},
+	}
+
+	for _, cmd := range app.Commands {
+		cmd.OnUsageError = onUsageError
+		for _, sub := range cmd.Subcommands {
+			sub.OnUsageError = onUsageError
+		}
+	}
+
+	args := reorderFlags(app, expandAlias(app, os.Args, cfg.Aliases))
+	if err := app.Run(args); err != nil {
+		reportError(logger, format, err)
+		cli.OsExiter(exitCode(err))
+	}
+}
+
+func getTask(r io.Reader, args ...string) ([]string, error) {
+	if len(args) > 0 {
+		return []string{strings.Join(args, " ")}, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("task input is required")
+	}
+
+	line := strings.TrimSpace(scanner.Text())
+	if line == "" {
+		return nil, fmt.Errorf("task cannot be blank")
+	}
+
+	return []string{line}, nil
+}
+
+// getChecklistItems reads Markdown checklist items ("- [ ] task") from r,
+// one per line, ignoring headings and any other lines.
+func getChecklistItems(r io.Reader) ([]todo.Item, error) {
+	lines, err := getTasksMultiline(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []todo.Item
+	for _, line := range lines {
+		if item, ok := todo.ParseChecklistItem(line); ok {
+			items = append(items, item)
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no checklist items found")
+	}
+
+	return items, nil
+}
+
+func getTasksMultiline(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	var tasks []string
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			tasks = append(tasks, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no task input provided")
+	}
+
+	return tasks, nil
+}
+
+// listEntry pairs an item with its 1-based position in the stored list, so
+// that filtered or sorted output still shows the number other commands expect.
+type listEntry struct {
+	Num   int
+	Item  todo.Item
+	Depth int
+	// SubDone and SubTotal count the item's subtasks for "(2/5 done)" rollups.
+	SubDone  int
+	SubTotal int
+}
+
+func newListEntry(list *todo.List, num int) listEntry {
+	done, total := list.Progress(num)
+	return listEntry{Num: num, Item: (*list)[num-1], SubDone: done, SubTotal: total}
+}
+
+// nestEntries reorders entries so that subtasks follow their parents and
+// records each entry's depth. Subtasks whose parent was filtered out are
+// shown at the top level.
+func nestEntries(entries []listEntry) []listEntry {
+	items := make([]todo.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = entry.Item
+	}
+
+	order, depths := todo.TreeOrder(items)
+
+	nested := make([]listEntry, len(order))
+	for n, i := range order {
+		nested[n] = entries[i]
+		nested[n].Depth = depths[n]
+	}
+
+	return nested
+}
+
+func sortEntries(entries []listEntry, by string) error {
+	var cmp func(a, b listEntry) int
+
+	switch by {
+	case "":
+		return nil
+	case "priority":
+		cmp = func(a, b listEntry) int {
+			return todo.ComparePriority(a.Item.Priority, b.Item.Priority)
+		}
+	case "created":
+		cmp = func(a, b listEntry) int {
+			return a.Item.CreatedAt.Compare(b.Item.CreatedAt)
+		}
+	case "due":
+		// Tasks without a due date go last.
+		cmp = func(a, b listEntry) int {
+			switch {
+			case a.Item.HasDue() && !b.Item.HasDue():
+				return -1
+			case !a.Item.HasDue() && b.Item.HasDue():
+				return 1
+			}
+			return a.Item.Due.Compare(b.Item.Due)
+		}
+	case "completed":
+		// Open tasks have no completion time, so list them after completed ones.
+		cmp = func(a, b listEntry) int {
+			switch {
+			case a.Item.Done && !b.Item.Done:
+				return -1
+			case !a.Item.Done && b.Item.Done:
+				return 1
+			}
+			return a.Item.CompletedAt.Compare(b.Item.CompletedAt)
+		}
+	default:
+		return fmt.Errorf("invalid sort field %q (expected priority, created, completed, or due)", by)
+	}
+
+	slices.SortStableFunc(entries, cmp)
+	return nil
+}
+
+func tagFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:  "tag",
+		Usage: "Select tasks with this +project or @context `TAG` (repeatable)",
+	}
+}
+
+func projectFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:  "project",
+		Usage: "Select tasks in this `PROJECT` (repeatable, leading + optional)",
+	}
+}
+
+// matchesTags reports whether an item carries every tag requested with
+// --tag and --project.
+func matchesTags(c *cli.Context, item todo.Item) bool {
+	for _, tag := range c.StringSlice("tag") {
+		if !item.HasTag(tag) {
+			return false
+		}
+	}
+
+	for _, project := range c.StringSlice("project") {
+		if !item.HasTag("+" + strings.TrimPrefix(project, "+")) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// selectTasks returns the positions a bulk command acts on. Tasks are chosen
+// either by the references in the arguments (numbers, IDs, or ranges such as
+// 5-8) or by selector flags (--tag, --project, and --done where defined), in
+// which case only items accepted by keep are included. Selecting nothing is
+// an error, so a typo can't silently turn into a no-op.
+func selectTasks(c *cli.Context, list *todo.List, keep func(todo.Item) bool) ([]int, error) {
+	hasSelectors := len(c.StringSlice("tag")) > 0 || len(c.StringSlice("project")) > 0 || c.Bool("done")
+
+	switch {
+	case c.NArg() > 0 && hasSelectors:
+		return nil, usageErrorf("cannot combine task numbers with selector flags")
+	case c.NArg() > 0:
+		return list.ResolveAll(c.Args().Slice())
+	case !hasSelectors:
+		return nil, usageErrorf("please provide task numbers, IDs, or a selector flag")
+	}
+
+	var nums []int
+	for i, item := range *list {
+		if keep(item) && matchesTags(c, item) {
+			nums = append(nums, i+1)
+		}
+	}
+
+	if len(nums) == 0 {
+		return nil, fmt.Errorf("no tasks match the given selectors")
+	}
+
+	return nums, nil
+}
+
+// withOpenDescendants adds the open subtasks of each selected task after it.
+func withOpenDescendants(list *todo.List, nums []int) []int {
+	var result []int
+	seen := make(map[int]bool)
+
+	for _, num := range nums {
+		for _, n := range append([]int{num}, list.Descendants(num)...) {
+			if seen[n] || (n != num && (*list)[n-1].Done) {
+				continue
+			}
+			seen[n] = true
+			result = append(result, n)
+		}
+	}
+
+	return result
+}
+
+// formatEntry renders a single list line, e.g.
+// "[kxbt] 3. [ ] (A) ship release +v2 (due 2026-11-01)".
+func formatEntry(entry listEntry, now time.Time) string {
+	var b strings.Builder
+	item := entry.Item
+
+	status := "[ ]"
+	if item.Done {
+		status = "[x]"
+	}
+	b.WriteString(strings.Repeat("  ", entry.Depth))
+	fmt.Fprintf(&b, "[%s] %d. %s ", item.ID, entry.Num, status)
+
+	if item.Priority != "" {
+		fmt.Fprintf(&b, "(%s) ", item.Priority)
+	}
+	b.WriteString(item.Task)
+
+	if entry.SubTotal > 0 {
+		fmt.Fprintf(&b, " (%d/%d done)", entry.SubDone, entry.SubTotal)
+	}
+
+	if item.HasDue() {
+		if item.IsOverdue(now) {
+			fmt.Fprintf(&b, " (due %s, OVERDUE)", item.Due.Format(todo.DateLayout))
+		} else {
+			fmt.Fprintf(&b, " (due %s)", item.Due.Format(todo.DateLayout))
+		}
 	}
 
-	return ".todo.json"
+	return b.String()
+}
+
+func validateColorMode(mode string) error {
+	switch mode {
+	case "auto", "always", "never":
+		return nil
+	}
+	return usageErrorf("invalid color mode %q (expected auto, always, or never)", mode)
+}
+
+// colorEnabled reports whether output to f should get ANSI colors. The
+// --color flag wins, then NO_COLOR, then the color setting; by default
+// only terminals get colors.
+func colorEnabled(c *cli.Context, f *os.File) bool {
+	mode := c.String("color")
+	if !c.IsSet("color") {
+		switch {
+		case os.Getenv("NO_COLOR") != "":
+			mode = "never"
+		case configFrom(c).Color != "":
+			mode = configFrom(c).Color
+		}
+	}
+
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	return isTerminal(f)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// listStore gives the interactive UI the same locked, journaled access to
+// the todo file as the other commands. Changes are journaled under the UI
+// action that made them, e.g. "toggle ab12".
+type listStore struct {
+	c *cli.Context
+}
+
+func (s listStore) Load() (todo.List, error) {
+	list, _, err := loadTodoList(s.c)
+	if err != nil {
+		return nil, err
+	}
+	return *list, nil
+}
+
+func (s listStore) Update(action string, fn func(list *todo.List) error) error {
+	return fileStore{getTodoFileName(s.c)}.Update(action, fn)
+}
+
+// fileStore gives the HTTP API the same locked, journaled access to a todo
+// file as the other commands. Changes are journaled under the API request
+// that made them, e.g. "PATCH /tasks/ab12".
+type fileStore struct {
+	file string
+}
+
+func (s fileStore) Load() (todo.List, error) {
+	list := todo.List{}
+	if err := list.Load(s.file); err != nil {
+		return nil, storageErrorf("failed to load tasks: %w", err)
+	}
+	return list, nil
+}
+
+func (s fileStore) Update(action string, fn func(list *todo.List) error) error {
+	unlock, err := todo.Lock(s.file)
+	if err != nil {
+		return storageErrorf("failed to lock tasks: %w", err)
+	}
+	defer unlock()
+
+	return updateLockedFileAs(s.file, action, fn)
+}
+
+// isLoopback reports whether addr only accepts local connections.
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
+
+// highlight wraps the given byte ranges of text in bold yellow.
+func highlight(text string, ranges [][]int) string {
+	var b strings.Builder
+	last := 0
+
+	for _, r := range ranges {
+		b.WriteString(text[last:r[0]])
+		b.WriteString("\x1b[1;33m" + text[r[0]:r[1]] + "\x1b[0m")
+		last = r[1]
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+// agendaBuckets lists the agenda groups in display order.
+var agendaBuckets = []string{"Overdue", "Today", "This week", "Later"}
+
+// groupAgenda sorts open tasks into agenda buckets by due date. Tasks
+// without a due date fall into "Later".
+func groupAgenda(list *todo.List, now time.Time, keep func(todo.Item) bool) map[string][]listEntry {
+	today := todo.StartOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	nextWeek := today.AddDate(0, 0, 7)
+
+	groups := make(map[string][]listEntry)
+	for i, item := range *list {
+		if item.Done || !keep(item) {
+			continue
+		}
+
+		var bucket string
+		switch {
+		case !item.HasDue():
+			bucket = "Later"
+		case item.Due.Before(today):
+			bucket = "Overdue"
+		case item.Due.Before(tomorrow):
+			bucket = "Today"
+		case item.Due.Before(nextWeek):
+			bucket = "This week"
+		default:
+			bucket = "Later"
+		}
+
+		groups[bucket] = append(groups[bucket], newListEntry(list, i+1))
+	}
+
+	for _, entries := range groups {
+		_ = sortEntries(entries, "due")
+	}
+
+	return groups
+}
+
+// editWholeList opens the entire list in the user's editor and applies the
+// edited document back to it.
+func editWholeList(c *cli.Context) error {
+	list, _, err := loadTodoList(c)
+	if err != nil {
+		return err
+	}
+
+	doc, shown := listDocument(list)
+	edited, err := editText(doc)
+	if err != nil {
+		return err
+	}
+
+	edits, err := parseListDocument(strings.NewReader(edited), shown)
+	if err != nil {
+		return err
+	}
+
+	err = updateTodoList(c, func(list *todo.List) error {
+		return applyListDocument(list, shown, edits)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Updated task list.")
+	return nil
+}
+
+// openInput opens a file for reading, treating "-" as standard input.
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+func loadTodoList(c *cli.Context) (*todo.List, string, error) {
+	file := getTodoFileName(c)
+	list := &todo.List{}
+	if err := list.Load(file); err != nil {
+		return nil, "", storageErrorf("failed to load tasks: %w", err)
+	}
+	return list, file, nil
+}
+
+// loadArchive reads the tasks archived from the current todo file.
+func loadArchive(c *cli.Context) (*todo.List, error) {
+	items, err := todo.ReadArchive(todo.ArchiveFile(getTodoFileName(c)))
+	if err != nil {
+		return nil, storageErrorf("failed to load archive: %w", err)
+	}
+
+	list := todo.List(items)
+	return &list, nil
+}
+
+// reorderTask moves the task ref names to the position chosen by to, which
+// is given the list so it can pick a position relative to its length.
+func reorderTask(c *cli.Context, ref string, to func(list *todo.List) int) error {
+	var from, position int
+	var id string
+	err := updateTodoList(c, func(list *todo.List) error {
+		var err error
+		if from, err = list.Resolve(ref); err != nil {
+			return fmt.Errorf("failed to move task: %w", err)
+		}
+		id = (*list)[from-1].ID
+
+		position = to(list)
+		if err := list.Move(from, position); err != nil {
+			return fmt.Errorf("failed to move task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Moved task #%d [%s] to position %d.\n", from, id, position)
+	return nil
+}
+
+// updateTodoList loads the todo list, applies fn, and saves the result, all
+// while holding the file lock so concurrent invocations can't lose updates.
+// Nothing is saved if fn returns an error. The change is recorded in the
+// journal under the running command so it can be undone.
+func updateTodoList(c *cli.Context, fn func(list *todo.List) error) error {
+	file := getTodoFileName(c)
+
+	unlock, err := todo.Lock(file)
+	if err != nil {
+		return storageErrorf("failed to lock tasks: %w", err)
+	}
+	defer unlock()
+
+	return updateLockedFile(c, file, fn)
+}
+
+// updateLockedFile is updateTodoList for a file whose lock the caller
+// already holds.
+func updateLockedFile(c *cli.Context, file string, fn func(list *todo.List) error) error {
+	command := strings.Join(append([]string{c.Command.Name}, c.Args().Slice()...), " ")
+	return updateLockedFileAs(file, command, fn)
+}
+
+// updateTodoListFinal is updateTodoList for a change that can't be undone
+// because it also changes other files. It is still shown in the history.
+func updateTodoListFinal(c *cli.Context, fn func(list *todo.List) error) error {
+	file := getTodoFileName(c)
+
+	unlock, err := todo.Lock(file)
+	if err != nil {
+		return storageErrorf("failed to lock tasks: %w", err)
+	}
+	defer unlock()
+
+	command := strings.Join(append([]string{c.Command.Name}, c.Args().Slice()...), " ")
+	return updateLocked(file, command, (*todo.Journal).RecordFinal, fn)
+}
+
+// updateLockedFileAs is updateLockedFile recording the change in the
+// journal under the given command.
+func updateLockedFileAs(file, command string, fn func(list *todo.List) error) error {
+	return updateLocked(file, command, (*todo.Journal).Record, fn)
+}
+
+// updateLocked applies fn to file, whose lock the caller holds, and records
+// the change in its journal with record.
+func updateLocked(file, command string, record func(j *todo.Journal, command string, before, after todo.List), fn func(list *todo.List) error) error {
+	list := &todo.List{}
+	if err := list.Get(file); err != nil {
+		return storageErrorf("failed to load tasks: %w", err)
+	}
+
+	before := list.Clone()
+
+	if err := fn(list); err != nil {
+		return err
+	}
+
+	if err := list.Save(file); err != nil {
+		return storageErrorf("failed to save list: %w", err)
+	}
+
+	// The change is saved by now, so failing to record it is only worth a
+	// warning: reporting an error would suggest the command didn't work.
+	if err := recordChange(file, func(j *todo.Journal) { record(j, command, before, *list) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the change was saved but can't be undone: %v\n", err)
+	}
+
+	return nil
+}
+
+// recordChange adds a change to the journal kept next to file.
+func recordChange(file string, record func(j *todo.Journal)) error {
+	journalFile := todo.JournalFile(file)
+	journal := &todo.Journal{}
+	if err := journal.Get(journalFile); err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+
+	record(journal)
+
+	if err := journal.Save(journalFile); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
+
+// replayJournal undoes or redoes the latest operation under the file lock,
+// returning the command that was reverted or reapplied.
+func replayJournal(c *cli.Context, step func(j *todo.Journal, current todo.List) (todo.List, todo.Operation, error)) (string, error) {
+	file := getTodoFileName(c)
+
+	unlock, err := todo.Lock(file)
+	if err != nil {
+		return "", storageErrorf("failed to lock tasks: %w", err)
+	}
+	defer unlock()
+
+	list := todo.List{}
+	if err := list.Get(file); err != nil {
+		return "", storageErrorf("failed to load tasks: %w", err)
+	}
+
+	journalFile := todo.JournalFile(file)
+	journal := &todo.Journal{}
+	if err := journal.Get(journalFile); err != nil {
+		return "", storageErrorf("failed to load history: %w", err)
+	}
+
+	restored, op, err := step(journal, list)
+	if err != nil {
+		return "", err
+	}
+
+	if err := restored.Save(file); err != nil {
+		return "", storageErrorf("failed to save list: %w", err)
+	}
+
+	if err := journal.Save(journalFile); err != nil {
+		return "", storageErrorf("failed to save history: %w", err)
+	}
+
+	return op.Command, nil
+}
+
+// getTodoFileName returns the todo file for this invocation; see
+// resolveTodoFile for how it is chosen.
+func getTodoFileName(c *cli.Context) string {
+	return resolveTodoFile(c).File
 }
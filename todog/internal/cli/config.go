@@ -0,0 +1,404 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the settings file kept in the todog directory.
+const configFileName = "config.yaml"
+
+// config holds the settings read from the config file. Flags override
+// environment variables, which override these settings, which override
+// built-in defaults.
+type config struct {
+	// File is a todo file to use instead of a named list, like TODOG_FILE.
+	File string `yaml:"file,omitempty"`
+	// DefaultList names the list to use, like TODOG_LIST.
+	DefaultList string `yaml:"default_list,omitempty"`
+	// DateFormat is the Go time layout for timestamps in verbose listings.
+	DateFormat string `yaml:"date_format,omitempty"`
+	// Color is auto, always, or never, like the --color flag.
+	Color string `yaml:"color,omitempty"`
+	// List holds default flags for the list command.
+	List listDefaults `yaml:"list,omitempty"`
+	// Aliases maps extra command names to a command and its arguments,
+	// e.g. "ls: list --hide-completed".
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+}
+
+type listDefaults struct {
+	HideCompleted bool   `yaml:"hide_completed,omitempty"`
+	Verbose       bool   `yaml:"verbose,omitempty"`
+	Sort          string `yaml:"sort,omitempty"`
+}
+
+// configKey describes a setting that "todog config" can read and change.
+type configKey struct {
+	name  string
+	usage string
+	get   func(cfg *config) string
+	set   func(cfg *config, value string) error
+}
+
+var configKeys = []configKey{
+	{
+		name:  "file",
+		usage: "todo file to use instead of a named list",
+		get:   func(cfg *config) string { return cfg.File },
+		set: func(cfg *config, value string) error {
+			cfg.File = value
+			return nil
+		},
+	},
+	{
+		name:  "default_list",
+		usage: "named list to use when no other is chosen",
+		get:   func(cfg *config) string { return cfg.DefaultList },
+		set: func(cfg *config, value string) error {
+			if value != "" {
+				if err := validateListName(value); err != nil {
+					return err
+				}
+			}
+			cfg.DefaultList = value
+			return nil
+		},
+	},
+	{
+		name:  "date_format",
+		usage: "Go time layout for timestamps in verbose listings",
+		get:   func(cfg *config) string { return cfg.DateFormat },
+		set: func(cfg *config, value string) error {
+			cfg.DateFormat = value
+			return nil
+		},
+	},
+	{
+		name:  "color",
+		usage: "highlight output: auto, always, or never",
+		get:   func(cfg *config) string { return cfg.Color },
+		set: func(cfg *config, value string) error {
+			if value != "" {
+				if err := validateColorMode(value); err != nil {
+					return err
+				}
+			}
+			cfg.Color = value
+			return nil
+		},
+	},
+	{
+		name:  "list.hide_completed",
+		usage: "hide completed tasks in list by default",
+		get:   func(cfg *config) string { return formatBool(cfg.List.HideCompleted) },
+		set: func(cfg *config, value string) (err error) {
+			cfg.List.HideCompleted, err = parseBool(value)
+			return err
+		},
+	},
+	{
+		name:  "list.verbose",
+		usage: "show timestamps in list by default",
+		get:   func(cfg *config) string { return formatBool(cfg.List.Verbose) },
+		set: func(cfg *config, value string) (err error) {
+			cfg.List.Verbose, err = parseBool(value)
+			return err
+		},
+	},
+	{
+		name:  "list.sort",
+		usage: "default sort field for list",
+		get:   func(cfg *config) string { return cfg.List.Sort },
+		set: func(cfg *config, value string) error {
+			if err := sortEntries(nil, value); err != nil {
+				return usageError(err)
+			}
+			cfg.List.Sort = value
+			return nil
+		},
+	},
+}
+
+// configKeysHelp describes the settings for the config command's help.
+func configKeysHelp() string {
+	var b strings.Builder
+	for _, key := range configKeys {
+		fmt.Fprintf(&b, "   %-20s %s\n", key.name, key.usage)
+	}
+	fmt.Fprintf(&b, "   %-20s %s", aliasPrefix+"NAME", "command and arguments run by \"todog NAME\"")
+	return b.String()
+}
+
+// aliasPrefix starts config keys that define command aliases.
+const aliasPrefix = "aliases."
+
+func formatBool(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
+
+func parseBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, usageErrorf("invalid boolean %q (expected true or false)", value)
+	}
+	return b, nil
+}
+
+func findConfigKey(name string) (configKey, error) {
+	for _, key := range configKeys {
+		if key.name == name {
+			return key, nil
+		}
+	}
+	return configKey{}, usageErrorf("unknown config key %q", name)
+}
+
+// getConfigValue returns a setting by key, including aliases.NAME keys.
+func getConfigValue(cfg *config, name string) (string, error) {
+	if alias, ok := strings.CutPrefix(name, aliasPrefix); ok {
+		return cfg.Aliases[alias], nil
+	}
+
+	key, err := findConfigKey(name)
+	if err != nil {
+		return "", err
+	}
+	return key.get(cfg), nil
+}
+
+// setConfigValue changes a setting by key. An empty value unsets it.
+func setConfigValue(c *cli.Context, cfg *config, name, value string) error {
+	if alias, ok := strings.CutPrefix(name, aliasPrefix); ok {
+		if alias == "" || strings.ContainsAny(alias, " \t") || strings.HasPrefix(alias, "-") {
+			return usageErrorf("invalid alias name %q", alias)
+		}
+		if c.App.Command(alias) != nil {
+			return usageErrorf("cannot redefine the %s command", alias)
+		}
+
+		if value == "" {
+			delete(cfg.Aliases, alias)
+			return nil
+		}
+		if cfg.Aliases == nil {
+			cfg.Aliases = make(map[string]string)
+		}
+		cfg.Aliases[alias] = value
+		return nil
+	}
+
+	key, err := findConfigKey(name)
+	if err != nil {
+		return err
+	}
+	return key.set(cfg, value)
+}
+
+// configPath returns the config file location: TODOG_CONFIG if set, else
+// config.yaml in the todog directory. It returns "" when there is none,
+// which is always the case in the test environment.
+func configPath() string {
+	if path := os.Getenv("TODOG_CONFIG"); path != "" {
+		return path
+	}
+
+	if os.Getenv("TODOG_ENV") == "test" {
+		return ""
+	}
+
+	dir, ok := todogDirPath()
+	if !ok {
+		return ""
+	}
+	return filepath.Join(dir, configFileName)
+}
+
+// invalidConfigError reports a config file that can't be parsed.
+type invalidConfigError struct {
+	path string
+	err  error
+}
+
+func (e *invalidConfigError) Error() string {
+	return fmt.Sprintf("invalid config file %s: %v", e.path, e.err)
+}
+
+func (e *invalidConfigError) Unwrap() error { return e.err }
+
+// loadConfig reads the config file. A missing file yields empty settings.
+func loadConfig() (*config, error) {
+	cfg := &config{}
+
+	path := configPath()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, storageErrorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, &invalidConfigError{path, err}
+	}
+
+	return cfg, nil
+}
+
+// replacesConfig reports whether args run "todog config set" or "config
+// unset", which can start over from an unparsable config file.
+func replacesConfig(args []string) bool {
+	return len(args) >= 2 && args[0] == "config" && (args[1] == "set" || args[1] == "unset")
+}
+
+// setAsideConfig renames an unparsable config file to path.bak so that
+// "todog config set" and "config unset" can start a new one.
+func setAsideConfig(invalid *invalidConfigError) error {
+	backup := invalid.path + ".bak"
+	if err := os.Rename(invalid.path, backup); err != nil {
+		return storageErrorf("failed to set aside invalid config: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %v; moved it to %s and starting a new one.\n", invalid, backup)
+	return nil
+}
+
+// saveConfig writes the settings back to the config file.
+func saveConfig(cfg *config) error {
+	path := configPath()
+	if path == "" {
+		return errors.New("cannot find a home directory to save the config in")
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return storageErrorf("failed to save config: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return storageErrorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// configFrom returns the settings loaded by Execute.
+func configFrom(c *cli.Context) *config {
+	if cfg, ok := c.App.Metadata["config"].(*config); ok {
+		return cfg
+	}
+	return &config{}
+}
+
+// dateFormat returns the layout for timestamps in verbose listings.
+func (cfg *config) dateFormat() string {
+	if cfg.DateFormat == "" {
+		return time.RFC3339
+	}
+	return cfg.DateFormat
+}
+
+// expandAlias replaces an aliased command name in args with the command
+// and arguments it stands for, split like a shell would split them, so
+// "add 'buy milk' --due today" keeps buy milk as one argument. Global
+// flags before the command are kept; builtin commands always win over
+// aliases.
+func expandAlias(app *cli.App, args []string, aliases map[string]string) []string {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		if strings.HasPrefix(arg, "-") {
+			// Skip the value of a global flag given as a separate argument.
+			if !strings.Contains(arg, "=") && flagTakesValue(app.Flags, arg) {
+				i++
+			}
+			continue
+		}
+
+		expansion, ok := aliases[arg]
+		if !ok || app.Command(arg) != nil {
+			return args
+		}
+
+		expanded := append([]string{}, args[:i]...)
+		expanded = append(expanded, splitArgs(expansion)...)
+		return append(expanded, args[i+1:]...)
+	}
+
+	return args
+}
+
+// splitArgs splits s into arguments at unquoted whitespace. Single quotes
+// keep everything up to the closing quote; double quotes do too, except
+// that a backslash escapes a following quote or backslash. Outside quotes
+// a backslash escapes any character.
+func splitArgs(s string) []string {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+				i++
+				arg.WriteRune(runes[i])
+			default:
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			arg.WriteRune(runes[i])
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestExpandAlias(t *testing.T) {
+	app := &cli.App{
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "list", Aliases: []string{"l"}},
+			&cli.BoolFlag{Name: "verbose"},
+		},
+		Commands: []*cli.Command{{Name: "list"}, {Name: "add"}},
+	}
+	aliases := map[string]string{
+		"ls":   "list --hide-completed",
+		"add":  "list",
+		"work": "add --priority A",
+		"milk": "add 'buy milk' @store",
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			[]string{"todog", "ls"},
+			[]string{"todog", "list", "--hide-completed"},
+		},
+		{
+			[]string{"todog", "--list", "ls", "ls", "--sort", "due"},
+			[]string{"todog", "--list", "ls", "list", "--hide-completed", "--sort", "due"},
+		},
+		{
+			[]string{"todog", "-l=home", "--verbose", "work", "Ship it"},
+			[]string{"todog", "-l=home", "--verbose", "add", "--priority", "A", "Ship it"},
+		},
+		{
+			[]string{"todog", "milk"},
+			[]string{"todog", "add", "buy milk", "@store"},
+		},
+		{
+			// Builtin commands can't be shadowed.
+			[]string{"todog", "add", "Ship it"},
+			[]string{"todog", "add", "Ship it"},
+		},
+		{
+			[]string{"todog", "list", "ls"},
+			[]string{"todog", "list", "ls"},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, expandAlias(app, tt.args, aliases))
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"list --hide-completed", []string{"list", "--hide-completed"}},
+		{"  add   'buy milk'  --due today ", []string{"add", "buy milk", "--due", "today"}},
+		{`add "say \"hi\"" +home`, []string{"add", `say "hi"`, "+home"}},
+		{`add it\'s\ done`, []string{"add", "it's done"}},
+		{`search ''`, []string{"search", ""}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, splitArgs(tt.input), tt.input)
+	}
+}
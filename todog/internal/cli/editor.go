@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+const listDocumentHeader = `# Edit your tasks below, one per line as "ID task text".
+# Change the text to edit a task, delete a line to delete the task, or add
+# a line without an ID to create a new task. Lines starting with # are ignored.
+`
+
+// editText opens the user's editor on text and returns the saved result.
+func editText(text string) (string, error) {
+	tmp, err := os.CreateTemp("", "todog-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	// Split so that values like "code --wait" work.
+	args := append(strings.Fields(editorCommand()), tmp.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", args[0], err)
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// listDocument renders the list as an editable text document. It also
+// returns the set of IDs it contains, which parseListDocument and
+// applyListDocument use to tell edited, deleted, and new lines apart.
+func listDocument(list *todo.List) (string, map[string]bool) {
+	var b strings.Builder
+	b.WriteString(listDocumentHeader)
+
+	shown := make(map[string]bool)
+	for _, item := range *list {
+		fmt.Fprintf(&b, "%s %s\n", item.ID, item.Task)
+		shown[item.ID] = true
+	}
+
+	return b.String(), shown
+}
+
+// documentEdit is a single line read back from an edited list document.
+// ID is empty for lines that add a new task.
+type documentEdit struct {
+	ID   string
+	Task string
+}
+
+// parseListDocument reads an edited list document. A line whose first word
+// is one of the shown IDs edits that task; any other line adds one.
+func parseListDocument(r io.Reader, shown map[string]bool) ([]documentEdit, error) {
+	var edits []documentEdit
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, task, _ := strings.Cut(line, " ")
+		if !shown[id] {
+			edits = append(edits, documentEdit{Task: line})
+			continue
+		}
+
+		if seen[id] {
+			return nil, fmt.Errorf("line %d: task %s appears more than once", n, id)
+		}
+		seen[id] = true
+
+		task = strings.TrimSpace(task)
+		if task == "" {
+			return nil, fmt.Errorf("line %d: task cannot be blank", n)
+		}
+
+		edits = append(edits, documentEdit{ID: id, Task: task})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+// applyListDocument edits, deletes, and adds tasks so that list matches the
+// edited document. Shown tasks whose lines were removed are deleted; tasks
+// added by someone else while the editor was open are left alone.
+func applyListDocument(list *todo.List, shown map[string]bool, edits []documentEdit) error {
+	keep := make(map[string]string)
+	for _, e := range edits {
+		if e.ID != "" {
+			keep[e.ID] = e.Task
+		}
+	}
+
+	// Delete from the end so earlier positions stay valid.
+	for i := len(*list); i >= 1; i-- {
+		item := (*list)[i-1]
+
+		task, ok := keep[item.ID]
+		if !ok && shown[item.ID] {
+			if err := list.Delete(i); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if ok && task != item.Task {
+			if err := list.Edit(i, task); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, e := range edits {
+		if e.ID == "" {
+			list.Add(e.Task)
+		}
+	}
+
+	return nil
+}
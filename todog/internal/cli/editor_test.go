@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestListDocumentRoundTrip(t *testing.T) {
+	var list todo.List
+	keep := list.Add("Keep me")
+	fix := list.Add("Fix tpyo")
+	drop := list.Add("Drop me")
+
+	doc, shown := listDocument(&list)
+	assert.Contains(t, doc, keep.ID+" Keep me\n")
+
+	doc = strings.Replace(doc, fix.ID+" Fix tpyo", fix.ID+" Fix typo", 1)
+	doc = strings.Replace(doc, drop.ID+" Drop me\n", "", 1)
+	doc += "Brand new task\n"
+
+	edits, err := parseListDocument(strings.NewReader(doc), shown)
+	require.NoError(t, err)
+
+	// Simulate a task added by another invocation while the editor was open.
+	concurrent := list.Add("Added meanwhile")
+
+	require.NoError(t, applyListDocument(&list, shown, edits))
+
+	require.Len(t, list, 4)
+	assert.Equal(t, keep.ID, list[0].ID)
+	assert.Equal(t, "Fix typo", list[1].Task)
+	assert.Equal(t, fix.ID, list[1].ID)
+	assert.Equal(t, concurrent.ID, list[2].ID)
+	assert.Equal(t, "Brand new task", list[3].Task)
+}
+
+func TestParseListDocumentErrors(t *testing.T) {
+	shown := map[string]bool{"abcd": true}
+
+	_, err := parseListDocument(strings.NewReader("abcd\n"), shown)
+	assert.EqualError(t, err, "line 1: task cannot be blank")
+
+	_, err = parseListDocument(strings.NewReader("# comment\nabcd one\nabcd two\n"), shown)
+	assert.EqualError(t, err, "line 3: task abcd appears more than once")
+}
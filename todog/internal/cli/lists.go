@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+	"github.com/urfave/cli/v2"
+)
+
+// defaultListName is used when no list is chosen. Its file is the
+// todo.json that todog used before it had named lists.
+const defaultListName = "todo"
+
+// listNamePattern keeps list names usable as file names. Names can't
+// contain dots, so journals (todo.journal.json) never look like lists.
+var listNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validateListName(name string) error {
+	if !listNamePattern.MatchString(name) {
+		return usageErrorf("invalid list name %q (use letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// todogDir returns the directory holding named lists, creating it if
+// needed. It reports false when there is no home directory to use.
+func todogDir() (string, bool) {
+	dir, ok := todogDirPath()
+	if ok {
+		_ = os.MkdirAll(dir, 0755)
+	}
+	return dir, ok
+}
+
+// todogDirPath is todogDir without creating the directory.
+func todogDirPath() (string, bool) {
+	switch os.Getenv("TODOG_ENV") {
+	case "development":
+		return filepath.Join(".", "tmp"), true
+	case "test":
+		fmt.Fprintln(os.Stderr, "ERROR: TODOG_FILE must be set in test environment.")
+		os.Exit(1)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".todog"), true
+	}
+
+	return "", false
+}
+
+// listFileName returns the file storing the named list.
+func listFileName(name string) string {
+	dir, ok := todogDir()
+	if !ok {
+		return "." + name + ".json"
+	}
+	return filepath.Join(dir, name+".json")
+}
+
+// projectFileName is the per-directory todo file created by "todog init".
+// A .todog directory holding todo.json works too.
+const projectFileName = ".todog.json"
+
+// todoSource is the todo file an invocation uses and why it was chosen.
+type todoSource struct {
+	File string
+	// List is the named list stored in File, or empty for other files.
+	List   string
+	Reason string
+}
+
+// resolveTodoFile picks the todo file for this invocation, in order: the
+// --list flag, TODOG_FILE, TODOG_LIST, a project file in the current
+// directory or one of its parents, the file and default_list settings, and
+// finally the "todo" list.
+func resolveTodoFile(c *cli.Context) todoSource {
+	if name := c.String("list"); name != "" {
+		return todoSource{listFileName(name), name, "selected with --list"}
+	}
+
+	if path := os.Getenv("TODOG_FILE"); path != "" {
+		return todoSource{path, "", "set by TODOG_FILE"}
+	}
+
+	if name := os.Getenv("TODOG_LIST"); name != "" {
+		return todoSource{listFileName(name), name, "set by TODOG_LIST"}
+	}
+
+	if file, ok := findProjectFile(); ok {
+		return todoSource{file, "", "nearest project list at or above the current directory"}
+	}
+
+	cfg := configFrom(c)
+	if cfg.File != "" {
+		return todoSource{cfg.File, "", "set by file in " + configPath()}
+	}
+	if cfg.DefaultList != "" {
+		return todoSource{listFileName(cfg.DefaultList), cfg.DefaultList, "set by default_list in " + configPath()}
+	}
+
+	return todoSource{listFileName(defaultListName), defaultListName, "the default list"}
+}
+
+// findProjectFile looks for a .todog.json file or a .todog directory in the
+// current directory and then each of its parents, the way git finds .git.
+// The global ~/.todog directory doesn't count as a project.
+func findProjectFile() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	global := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		global = filepath.Join(home, ".todog")
+	}
+
+	for {
+		file := filepath.Join(dir, projectFileName)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, true
+		}
+
+		sub := filepath.Join(dir, ".todog")
+		if info, err := os.Stat(sub); err == nil && info.IsDir() && sub != global {
+			return filepath.Join(sub, "todo.json"), true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// setDefaultList saves the list used when no flag, environment variable,
+// or project file picks one.
+func setDefaultList(c *cli.Context, name string) error {
+	cfg := configFrom(c)
+	cfg.DefaultList = name
+	return saveConfig(cfg)
+}
+
+// listNames returns the names of the lists that have a file, sorted.
+func listNames() ([]string, error) {
+	dir, ok := todogDir()
+	if !ok {
+		return nil, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if listNamePattern.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// moveToList moves the selected tasks and all their subtasks from the
+// current list to the named one, returning them as they were and as they
+// now are. Both files stay locked throughout, and the destination is saved
+// first so that a failure can leave tasks in both lists but never in
+// neither.
+func moveToList(c *cli.Context, name string) (moved, added []listEntry, err error) {
+	src := getTodoFileName(c)
+	dst := listFileName(name)
+
+	if absPath(src) == absPath(dst) {
+		return nil, nil, usageErrorf("tasks are already in list %s", name)
+	}
+
+	// Lock in a fixed order so opposite moves can't deadlock.
+	files := []string{src, dst}
+	slices.Sort(files)
+	for _, file := range files {
+		unlock, err := todo.Lock(file)
+		if err != nil {
+			return nil, nil, storageErrorf("failed to lock tasks: %w", err)
+		}
+		defer unlock()
+	}
+
+	err = updateLockedFile(c, src, func(list *todo.List) error {
+		nums, err := selectTasks(c, list, func(todo.Item) bool { return true })
+		if err != nil {
+			return fmt.Errorf("failed to move task: %w", err)
+		}
+
+		ids := make(map[string]bool)
+		for _, num := range nums {
+			for _, n := range append([]int{num}, list.Descendants(num)...) {
+				item := (*list)[n-1]
+				if ids[item.ID] {
+					continue
+				}
+				ids[item.ID] = true
+				moved = append(moved, listEntry{Num: n, Item: item})
+			}
+		}
+
+		items := make([]todo.Item, len(moved))
+		for i, entry := range moved {
+			items[i] = entry.Item
+			// Tasks whose parent stays behind become top-level tasks.
+			if !ids[items[i].Parent] {
+				items[i].Parent = ""
+			}
+		}
+
+		err = updateLockedFile(c, dst, func(dest *todo.List) error {
+			start := len(*dest)
+			for i, item := range dest.Import(items) {
+				added = append(added, listEntry{Num: start + i + 1, Item: item})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Delete by ID, since each deletion shifts later positions.
+		for _, entry := range moved {
+			num, err := list.Resolve(entry.Item.ID)
+			if err != nil {
+				return fmt.Errorf("failed to move task: %w", err)
+			}
+			if err := list.Delete(num); err != nil {
+				return fmt.Errorf("failed to move task: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return moved, added, nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
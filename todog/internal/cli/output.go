@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+	"github.com/urfave/cli/v2"
+)
+
+// Exit codes returned by todog. They are part of the CLI's contract with
+// scripts, so existing values must not change.
+const (
+	exitFailure  = 1 // any error not covered below
+	exitUsage    = 2 // missing or invalid arguments, flags, or commands
+	exitNotFound = 3 // a task number or ID matches no task
+	exitStorage  = 4 // the todo file could not be read, written, or locked
+)
+
+const exitCodesHelp = `Exit codes:
+  0  success
+  1  general failure
+  2  usage error: missing or invalid arguments, flags, or commands
+  3  a task number or ID does not match any task
+  4  the todo file could not be read, written, or locked`
+
+// Output formats selectable with the global --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputTSV  = "tsv"
+)
+
+// machineOutputCommands are the commands that can print json or tsv; the
+// others only print text.
+var machineOutputCommands = []string{
+	"list", "search", "show", "add", "complete", "delete", "stats", "timesheet",
+}
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, args ...any) error {
+	return &exitError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+func usageError(err error) error {
+	return &exitError{code: exitUsage, err: err}
+}
+
+func storageErrorf(format string, args ...any) error {
+	return &exitError{code: exitStorage, err: fmt.Errorf(format, args...)}
+}
+
+// onUsageError marks flag parsing failures as usage errors.
+func onUsageError(_ *cli.Context, err error, _ bool) error {
+	return usageError(err)
+}
+
+// exitCode maps an error returned by a command to the process exit code.
+func exitCode(err error) int {
+	var notFound *todo.NotFoundError
+	var rangeErr *todo.RangeError
+	var exitErr *exitError
+
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &notFound):
+		return exitNotFound
+	case errors.As(err, &rangeErr):
+		return exitUsage
+	}
+
+	return exitFailure
+}
+
+func errorType(code int) string {
+	switch code {
+	case exitUsage:
+		return "usage"
+	case exitNotFound:
+		return "not_found"
+	case exitStorage:
+		return "storage"
+	}
+	return "failure"
+}
+
+// outputFormat returns the validated value of the global --output flag.
+func outputFormat(c *cli.Context) (string, error) {
+	switch format := c.String("output"); format {
+	case "", outputText:
+		return outputText, nil
+	case outputJSON, outputTSV:
+		return format, nil
+	default:
+		return "", usageErrorf("invalid output format %q (expected text, json, or tsv)", format)
+	}
+}
+
+// checkOutputFormat rejects a json or tsv format for a command that only
+// prints text.
+func checkOutputFormat(c *cli.Context, format string) error {
+	cmd := c.App.Command(c.Args().First())
+	if format == outputText || cmd == nil || slices.Contains(machineOutputCommands, cmd.Name) {
+		return nil
+	}
+	return usageErrorf("--output %s is not supported by %s (only by %s)",
+		format, cmd.Name, strings.Join(machineOutputCommands, ", "))
+}
+
+// taskResult is a task as reported in JSON output: the stored item plus
+// its current 1-based position.
+type taskResult struct {
+	Position int `json:"position"`
+	todo.Item
+}
+
+// commandResult is the JSON document written by commands that report tasks.
+type commandResult struct {
+	Command string       `json:"command"`
+	Tasks   []taskResult `json:"tasks"`
+	// Spawned lists new occurrences created by completing recurring tasks.
+	Spawned []taskResult `json:"spawned,omitempty"`
+}
+
+// errorResult is the JSON document written when a command fails.
+type errorResult struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func toTaskResults(entries []listEntry) []taskResult {
+	results := make([]taskResult, len(entries))
+	for i, entry := range entries {
+		results[i] = taskResult{Position: entry.Num, Item: entry.Item}
+	}
+	return results
+}
+
+// writeTasks reports the tasks a command listed or changed in a
+// machine-readable format. It is not used for text output, which each
+// command formats for humans itself.
+func writeTasks(w io.Writer, format, command string, entries, spawned []listEntry) error {
+	switch format {
+	case outputJSON:
+		result := commandResult{
+			Command: command,
+			Tasks:   toTaskResults(entries),
+			Spawned: toTaskResults(spawned),
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+
+	case outputTSV:
+		fmt.Fprintln(w, strings.Join([]string{
+			"position", "id", "done", "priority", "due", "created_at", "completed_at", "task",
+		}, "\t"))
+
+		for _, entry := range append(entries, spawned...) {
+			item := entry.Item
+
+			due := ""
+			if item.HasDue() {
+				due = item.Due.Format(todo.DateLayout)
+			}
+			completed := ""
+			if !item.CompletedAt.IsZero() {
+				completed = item.CompletedAt.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%t\t%s\t%s\t%s\t%s\t%s\n",
+				entry.Num, item.ID, item.Done, item.Priority, due,
+				item.CreatedAt.Format(time.RFC3339), completed, tsvField(item.Task))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// tsvField keeps a value on one line and in one column.
+func tsvField(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// reportError prints a failed command's error in the requested format.
+func reportError(logger *log.Logger, format string, err error) {
+	code := exitCode(err)
+
+	if format == outputJSON {
+		var result errorResult
+		result.Error.Type = errorType(code)
+		result.Error.Code = code
+		result.Error.Message = err.Error()
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(result)
+		return
+	}
+
+	logger.Printf("Error: %v", err)
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+// writeTaskDetails prints everything stored about the num-th task: its
+// fields and timestamps, then its notes and attachments.
+func writeTaskDetails(w io.Writer, list *todo.List, num int, now time.Time, cfg *config) error {
+	item := (*list)[num-1]
+	layout := cfg.dateFormat()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Task:\t%s\n", item.Task)
+	fmt.Fprintf(tw, "ID:\t%s\n", item.ID)
+	fmt.Fprintf(tw, "Position:\t%d\n", num)
+
+	status := "open"
+	if item.Done {
+		status = "completed"
+	}
+	fmt.Fprintf(tw, "Status:\t%s\n", status)
+
+	if item.Priority != "" {
+		fmt.Fprintf(tw, "Priority:\t%s\n", item.Priority)
+	}
+	if item.HasDue() {
+		due := item.Due.Format(todo.DateLayout)
+		if item.IsOverdue(now) {
+			due += " (OVERDUE)"
+		}
+		fmt.Fprintf(tw, "Due:\t%s\n", due)
+	}
+	if item.Recur != "" {
+		fmt.Fprintf(tw, "Repeats:\t%s\n", item.Recur)
+	}
+	if item.Parent != "" {
+		if p, err := list.Resolve(item.Parent); err == nil {
+			fmt.Fprintf(tw, "Parent:\t#%d [%s] %s\n", p, item.Parent, (*list)[p-1].Task)
+		}
+	}
+	if done, total := list.Progress(num); total > 0 {
+		fmt.Fprintf(tw, "Subtasks:\t%d/%d done\n", done, total)
+	}
+	if tags := append(append([]string(nil), item.Projects...), item.Contexts...); len(tags) > 0 {
+		fmt.Fprintf(tw, "Tags:\t%s\n", strings.Join(tags, " "))
+	}
+
+	fmt.Fprintf(tw, "Created:\t%s\n", item.CreatedAt.Format(layout))
+	for _, reopened := range item.ReopenedAt {
+		fmt.Fprintf(tw, "Reopened:\t%s\n", reopened.Format(layout))
+	}
+	if item.Done {
+		fmt.Fprintf(tw, "Completed:\t%s\n", item.CompletedAt.Format(layout))
+	}
+	if len(item.TimeLog) > 0 {
+		tracked := formatDuration(item.TrackedTime(now))
+		if item.Tracking() {
+			tracked += " (running)"
+		}
+		fmt.Fprintf(tw, "Tracked:\t%s\n", tracked)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(item.Notes) > 0 {
+		fmt.Fprintln(w, "\nNotes:")
+		for _, note := range item.Notes {
+			fmt.Fprintf(w, "  %s\n", note.CreatedAt.Format(layout))
+			for _, line := range strings.Split(note.Text, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+
+	if len(item.Attachments) > 0 {
+		fmt.Fprintln(w, "\nAttachments:")
+		for _, ref := range item.Attachments {
+			if !todo.IsURL(ref) {
+				if _, err := os.Stat(ref); err != nil {
+					ref += " (missing)"
+				}
+			}
+			fmt.Fprintf(w, "  %s\n", ref)
+		}
+	}
+
+	return nil
+}
+
+// attachmentRef returns the form in which an attachment is stored: URLs as
+// given and file paths made absolute, so they work from any directory.
+func attachmentRef(ref string) string {
+	if todo.IsURL(ref) {
+		return ref
+	}
+	return absPath(ref)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+// writeStats prints a stats report as text tables with sparklines, as a
+// JSON document, or as TSV rows of per-period counts.
+func writeStats(w io.Writer, format string, stats todo.Stats) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+
+	case outputTSV:
+		fmt.Fprintln(w, "start\tcreated\tcompleted\topen")
+		for _, p := range stats.Periods {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", p.Start.Format(todo.DateLayout), p.Created, p.Completed, p.Open)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Open:\t%d\n", stats.Open)
+	fmt.Fprintf(tw, "Completed:\t%d\n", stats.Completed)
+	fmt.Fprintf(tw, "Archived:\t%d\n", stats.Archived)
+	fmt.Fprintf(tw, "Median time to complete:\t%s\n", formatDuration(stats.MedianTimeToComplete))
+	fmt.Fprintf(tw, "Current streak:\t%s\n", plural(stats.CurrentStreak, "day"))
+	fmt.Fprintf(tw, "Longest streak:\t%s\n", plural(stats.LongestStreak, "day"))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	heading := "Day"
+	if stats.Period == todo.Week {
+		heading = "Week of"
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tCreated\tCompleted\tOpen\t\n", heading)
+
+	var created, completed, open []int
+	for _, p := range stats.Periods {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t\n", p.Start.Format(todo.DateLayout), p.Created, p.Completed, p.Open)
+		created = append(created, p.Created)
+		completed = append(completed, p.Completed)
+		open = append(open, p.Open)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Created\t%s\n", sparkline(created))
+	fmt.Fprintf(tw, "Completed\t%s\n", sparkline(completed))
+	fmt.Fprintf(tw, "Open (burndown)\t%s\n", sparkline(open))
+	return tw.Flush()
+}
+
+// sparkBars are the bar heights a sparkline is drawn with, lowest first.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a row of bars scaled to the largest one.
+func sparkline(values []int) string {
+	top := 0
+	for _, v := range values {
+		top = max(top, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if top > 0 {
+			i = v * (len(sparkBars) - 1) / top
+		}
+		b.WriteRune(sparkBars[i])
+	}
+	return b.String()
+}
+
+// formatDuration renders a duration in the two largest units, e.g. "2d 4h".
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "n/a"
+	}
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	if minutes == 0 {
+		return "<1m"
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+// timesheet is the time tracked between From and To, per task and per tag.
+// A zero From covers all time up to To.
+type timesheet struct {
+	From, To time.Time
+	Tasks    []todo.TimeTotal
+	Tags     []todo.TimeTotal
+}
+
+func (s timesheet) total() time.Duration {
+	var total time.Duration
+	for _, t := range s.Tasks {
+		total += t.Time
+	}
+	return total
+}
+
+type timesheetTaskJSON struct {
+	ID    string  `json:"id"`
+	Task  string  `json:"task"`
+	Hours float64 `json:"hours"`
+}
+
+type timesheetTagJSON struct {
+	Tag   string  `json:"tag"`
+	Hours float64 `json:"hours"`
+}
+
+type timesheetJSON struct {
+	From       *time.Time          `json:"from,omitempty"`
+	To         time.Time           `json:"to"`
+	TotalHours float64             `json:"total_hours"`
+	Tasks      []timesheetTaskJSON `json:"tasks"`
+	Tags       []timesheetTagJSON  `json:"tags"`
+}
+
+// writeTimesheet prints hours per task and per tag as text tables, as a
+// JSON document, or as TSV rows.
+func writeTimesheet(w io.Writer, format string, sheet timesheet) error {
+	switch format {
+	case outputJSON:
+		doc := timesheetJSON{
+			To:         sheet.To,
+			TotalHours: hours(sheet.total()),
+			Tasks:      []timesheetTaskJSON{},
+			Tags:       []timesheetTagJSON{},
+		}
+		if !sheet.From.IsZero() {
+			doc.From = &sheet.From
+		}
+		for _, t := range sheet.Tasks {
+			doc.Tasks = append(doc.Tasks, timesheetTaskJSON{t.ID, t.Name, hours(t.Time)})
+		}
+		for _, t := range sheet.Tags {
+			doc.Tags = append(doc.Tags, timesheetTagJSON{t.Name, hours(t.Time)})
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+
+	case outputTSV:
+		fmt.Fprintln(w, "kind\tid\tname\thours")
+		for _, t := range sheet.Tasks {
+			fmt.Fprintf(w, "task\t%s\t%s\t%.2f\n", t.ID, tsvField(t.Name), hours(t.Time))
+		}
+		for _, t := range sheet.Tags {
+			fmt.Fprintf(w, "tag\t\t%s\t%.2f\n", t.Name, hours(t.Time))
+		}
+		return nil
+	}
+
+	if sheet.From.IsZero() {
+		fmt.Fprintln(w, "Timesheet for all time")
+	} else {
+		fmt.Fprintf(w, "Timesheet for %s to %s\n", sheet.From.Format(todo.DateLayout), sheet.To.Format(todo.DateLayout))
+	}
+
+	if len(sheet.Tasks) == 0 {
+		fmt.Fprintln(w, "No time tracked.")
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Hours\tTask")
+	for _, t := range sheet.Tasks {
+		fmt.Fprintf(tw, "%.2f\t[%s] %s\n", hours(t.Time), t.ID, t.Name)
+	}
+	fmt.Fprintf(tw, "%.2f\tTotal\n", hours(sheet.total()))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(sheet.Tags) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Hours\tTag")
+	for _, t := range sheet.Tags {
+		fmt.Fprintf(tw, "%.2f\t%s\n", hours(t.Time), t.Name)
+	}
+	return tw.Flush()
+}
+
+// hours converts d to hours rounded to two decimal places.
+func hours(d time.Duration) float64 {
+	return float64(d.Round(36*time.Second)) / float64(time.Hour)
+}
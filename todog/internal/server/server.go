@@ -0,0 +1,390 @@
+// Package server exposes a todo list over a local HTTP/JSON API, so other
+// tools can read and change tasks without running the CLI.
+//
+// The API has one resource, /tasks:
+//
+//	GET    /tasks          list tasks, optionally ?done=true|false and ?tag=TAG
+//	POST   /tasks          add a task
+//	GET    /tasks/{ref}    get one task by position or ID
+//	PATCH  /tasks/{ref}    change a task's text, status, priority, due date,
+//	                       recurrence, or parent
+//	DELETE /tasks/{ref}    delete a task
+//
+// Tasks are reported as their stored JSON plus their current 1-based
+// position. Errors are reported as {"error": "message"}.
+//
+// The API has no authentication, so it only answers requests addressed to
+// a loopback host on its own port, refuses requests from web pages on
+// other sites, and only accepts application/json bodies. Together these
+// keep a browser from reaching it from another site.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+// Store loads and changes the todo list behind the API. Update applies fn
+// to the current list and saves the result, recording the change under
+// action; nothing is saved if fn fails. Implementations must make Update
+// safe to call from concurrent requests.
+type Store interface {
+	Load() (todo.List, error)
+	Update(action string, fn func(list *todo.List) error) error
+}
+
+// maxBodySize limits request bodies, which only ever hold one task.
+const maxBodySize = 1 << 20
+
+// Task is a task as reported by the API: the stored item plus its current
+// 1-based position.
+type Task struct {
+	Position int `json:"position"`
+	todo.Item
+}
+
+// TaskList is the response to GET /tasks.
+type TaskList struct {
+	Tasks []Task `json:"tasks"`
+}
+
+// CreateRequest is the body of POST /tasks. Only Task is required; Due
+// accepts anything "todog add --due" does, and Parent is a position or ID.
+type CreateRequest struct {
+	Task     string `json:"task"`
+	Priority string `json:"priority,omitempty"`
+	Due      string `json:"due,omitempty"`
+	Every    string `json:"every,omitempty"`
+	Parent   string `json:"parent,omitempty"`
+}
+
+// UpdateRequest is the body of PATCH /tasks/{ref}. Fields left out are
+// unchanged; an empty Priority, Due, Every, or Parent clears that field.
+type UpdateRequest struct {
+	Task     *string `json:"task,omitempty"`
+	Done     *bool   `json:"done,omitempty"`
+	Priority *string `json:"priority,omitempty"`
+	Due      *string `json:"due,omitempty"`
+	Every    *string `json:"every,omitempty"`
+	Parent   *string `json:"parent,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// requestError is a problem with the request itself, reported with a 4xx
+// status.
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return &requestError{http.StatusBadRequest, err}
+}
+
+type server struct {
+	store Store
+	now   func() time.Time
+	hosts []string // the loopback host:port pairs requests may address
+}
+
+// New returns a handler serving the API for the list in store to clients
+// on this machine connecting to port.
+func New(store Store, port int) http.Handler {
+	s := &server{store: store, now: time.Now}
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		s.hosts = append(s.hosts, net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", s.listTasks)
+	mux.HandleFunc("POST /tasks", s.createTask)
+	mux.HandleFunc("GET /tasks/{ref}", s.getTask)
+	mux.HandleFunc("PATCH /tasks/{ref}", s.updateTask)
+	mux.HandleFunc("DELETE /tasks/{ref}", s.deleteTask)
+	return s.checkOrigin(mux)
+}
+
+// checkOrigin refuses requests addressed to a host other than this server,
+// as a DNS rebinding attack would send, and requests from a web page on
+// another site.
+func (s *server) checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(s.hosts, r.Host) {
+			writeError(w, &requestError{http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host)})
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme != "http" || !slices.Contains(s.hosts, u.Host) {
+				writeError(w, &requestError{http.StatusForbidden, fmt.Errorf("origin %q is not allowed", origin)})
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) listTasks(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.Load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	var done *bool
+	if v := query.Get("done"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, badRequest(fmt.Errorf("invalid done filter %q (expected true or false)", v)))
+			return
+		}
+		done = &b
+	}
+	tag := query.Get("tag")
+
+	result := TaskList{Tasks: []Task{}}
+	for i, item := range list {
+		if done != nil && item.Done != *done {
+			continue
+		}
+		if tag != "" && !item.HasTag(tag) {
+			continue
+		}
+		result.Tasks = append(result.Tasks, Task{Position: i + 1, Item: item})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *server) getTask(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.Load()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	num, err := list.Resolve(r.PathValue("ref"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Task{Position: num, Item: list[num-1]})
+}
+
+func (s *server) createTask(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if strings.TrimSpace(req.Task) == "" {
+		writeError(w, badRequest(errors.New("task cannot be blank")))
+		return
+	}
+
+	var created Task
+	err := s.store.Update(action(r, ""), func(list *todo.List) error {
+		list.Add(strings.TrimSpace(req.Task))
+		num := len(*list)
+
+		if err := s.apply(list, num, UpdateRequest{
+			Priority: &req.Priority,
+			Due:      &req.Due,
+			Every:    &req.Every,
+			Parent:   &req.Parent,
+		}); err != nil {
+			return err
+		}
+
+		created = Task{Position: num, Item: (*list)[num-1]}
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/tasks/"+created.ID)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *server) updateTask(w http.ResponseWriter, r *http.Request) {
+	var req UpdateRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var updated Task
+	err := s.store.Update(action(r, r.PathValue("ref")), func(list *todo.List) error {
+		num, err := list.Resolve(r.PathValue("ref"))
+		if err != nil {
+			return err
+		}
+
+		if err := s.apply(list, num, req); err != nil {
+			return err
+		}
+
+		updated = Task{Position: num, Item: (*list)[num-1]}
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *server) deleteTask(w http.ResponseWriter, r *http.Request) {
+	err := s.store.Update(action(r, r.PathValue("ref")), func(list *todo.List) error {
+		num, err := list.Resolve(r.PathValue("ref"))
+		if err != nil {
+			return err
+		}
+		return list.Delete(num)
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apply makes the changes in req to the num-th task. Invalid values are
+// reported as request errors.
+func (s *server) apply(list *todo.List, num int, req UpdateRequest) error {
+	if req.Task != nil {
+		if err := list.Edit(num, *req.Task); err != nil {
+			return badRequest(err)
+		}
+	}
+
+	if req.Priority != nil {
+		if err := list.Prioritize(num, *req.Priority); err != nil {
+			return badRequest(err)
+		}
+	}
+
+	if req.Due != nil {
+		var due time.Time
+		if *req.Due != "" {
+			var err error
+			if due, err = todo.ParseDue(*req.Due, s.now()); err != nil {
+				return badRequest(err)
+			}
+		}
+		if err := list.SetDue(num, due); err != nil {
+			return err
+		}
+	}
+
+	if req.Every != nil {
+		if err := list.SetRecurrence(num, *req.Every); err != nil {
+			return badRequest(err)
+		}
+	}
+
+	if req.Parent != nil {
+		parent := 0
+		if *req.Parent != "" {
+			var err error
+			if parent, err = list.Resolve(*req.Parent); err != nil {
+				return badRequest(fmt.Errorf("failed to find parent task: %w", err))
+			}
+		}
+		if err := list.SetParent(num, parent); err != nil {
+			return badRequest(err)
+		}
+	}
+
+	// Change the status last so a recurring task spawns its next
+	// occurrence with the new settings.
+	if req.Done != nil && *req.Done != (*list)[num-1].Done {
+		if err := list.Toggle(num); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// action describes a request in the list's history, e.g. "PATCH /tasks/ab12".
+func action(r *http.Request, ref string) string {
+	if ref == "" {
+		return r.Method + " /tasks"
+	}
+	return r.Method + " /tasks/" + ref
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
+		return &requestError{
+			http.StatusUnsupportedMediaType,
+			fmt.Errorf("unsupported content type %q (expected application/json)", contentType),
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return badRequest(fmt.Errorf("invalid request body: %w", err))
+	}
+	if dec.More() {
+		return badRequest(errors.New("invalid request body: unexpected data after JSON object"))
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// writeError reports err with a status matching its kind: 4xx for bad
+// requests, 404 for unknown tasks, and 500 for everything else.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+
+	var reqErr *requestError
+	var notFound *todo.NotFoundError
+	switch {
+	case errors.As(err, &reqErr):
+		status = reqErr.status
+	case errors.As(err, &notFound):
+		status = http.StatusNotFound
+	}
+
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
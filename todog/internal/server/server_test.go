@@ -0,0 +1,297 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/server"
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+// memStore keeps the list in memory and records each update's action.
+type memStore struct {
+	mu      sync.Mutex
+	list    todo.List
+	actions []string
+}
+
+func (s *memStore) Load() (todo.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append(todo.List(nil), s.list...), nil
+}
+
+func (s *memStore) Update(action string, fn func(list *todo.List) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := append(todo.List(nil), s.list...)
+	if err := fn(&list); err != nil {
+		return err
+	}
+	s.list = list
+	s.actions = append(s.actions, action)
+	return nil
+}
+
+func newServer(t *testing.T, tasks ...string) (*httptest.Server, *memStore) {
+	t.Helper()
+
+	store := &memStore{}
+	for _, task := range tasks {
+		store.list.Add(task)
+	}
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config.Handler = server.New(store, ts.Listener.Addr().(*net.TCPAddr).Port)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts, store
+}
+
+// do sends a request with a JSON body, if any, and decodes the response.
+func do(t *testing.T, method, url, body string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return send(t, req)
+}
+
+func send(t *testing.T, req *http.Request) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var data json.RawMessage
+	if resp.StatusCode != http.StatusNoContent {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&data))
+	}
+	return resp, data
+}
+
+func decode[T any](t *testing.T, data []byte) T {
+	t.Helper()
+
+	var v T
+	require.NoError(t, json.Unmarshal(data, &v), string(data))
+	return v
+}
+
+func TestListTasks(t *testing.T) {
+	ts, store := newServer(t, "Write report +work", "Buy milk @home", "Review PR +work")
+	require.NoError(t, store.list.Complete(3))
+
+	resp, data := do(t, "GET", ts.URL+"/tasks", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	result := decode[server.TaskList](t, data)
+	require.Len(t, result.Tasks, 3)
+	assert.Equal(t, 2, result.Tasks[1].Position)
+	assert.Equal(t, "Buy milk @home", result.Tasks[1].Task)
+
+	_, data = do(t, "GET", ts.URL+"/tasks?tag=work&done=false", "")
+	result = decode[server.TaskList](t, data)
+	require.Len(t, result.Tasks, 1)
+	assert.Equal(t, "Write report +work", result.Tasks[0].Task)
+
+	resp, _ = do(t, "GET", ts.URL+"/tasks?done=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// An empty list is an empty array, not null.
+	ts, _ = newServer(t)
+	_, data = do(t, "GET", ts.URL+"/tasks", "")
+	assert.JSONEq(t, `{"tasks": []}`, string(data))
+}
+
+func TestGetTask(t *testing.T) {
+	ts, store := newServer(t, "Write report", "Buy milk")
+	id := store.list[1].ID
+
+	for _, ref := range []string{"2", id} {
+		resp, data := do(t, "GET", ts.URL+"/tasks/"+ref, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		task := decode[server.Task](t, data)
+		assert.Equal(t, 2, task.Position)
+		assert.Equal(t, "Buy milk", task.Task)
+	}
+
+	resp, data := do(t, "GET", ts.URL+"/tasks/zzzz", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.JSONEq(t, `{"error": "item \"zzzz\" does not exist"}`, string(data))
+}
+
+func TestCreateTask(t *testing.T) {
+	ts, store := newServer(t, "Plan release")
+
+	resp, data := do(t, "POST", ts.URL+"/tasks",
+		`{"task": "Write notes +release", "priority": "b", "due": "2026-11-01", "parent": "1"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	task := decode[server.Task](t, data)
+	assert.Equal(t, "/tasks/"+task.ID, resp.Header.Get("Location"))
+	assert.Equal(t, 2, task.Position)
+	assert.Equal(t, "B", task.Priority)
+	assert.Equal(t, "2026-11-01", task.Due.Format(todo.DateLayout))
+	assert.Equal(t, []string{"+release"}, task.Projects)
+	assert.Equal(t, store.list[0].ID, task.Parent)
+	assert.Equal(t, []string{"POST /tasks"}, store.actions)
+
+	for _, body := range []string{
+		`{"task": "  "}`,
+		`{"task": "Bad priority", "priority": "high"}`,
+		`{"task": "Bad due", "due": "someday"}`,
+		`{"task": "Bad parent", "parent": "9"}`,
+		`{"text": "Unknown field"}`,
+		`{"task": "Trailing"} {}`,
+		`not json`,
+	} {
+		resp, data := do(t, "POST", ts.URL+"/tasks", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+		assert.Contains(t, string(data), `"error"`, body)
+	}
+
+	// Failed requests change nothing.
+	assert.Len(t, store.list, 2)
+}
+
+func TestUpdateTask(t *testing.T) {
+	ts, store := newServer(t, "Write report", "Water plants")
+	require.NoError(t, store.list.SetRecurrence(2, "daily"))
+	id := store.list[0].ID
+
+	resp, data := do(t, "PATCH", ts.URL+"/tasks/"+id, `{"task": "Write final report +work", "priority": "A"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	task := decode[server.Task](t, data)
+	assert.Equal(t, "Write final report +work", task.Task)
+	assert.Equal(t, "A", task.Priority)
+	assert.Equal(t, []string{"+work"}, task.Projects)
+
+	_, data = do(t, "PATCH", ts.URL+"/tasks/1", `{"done": true}`)
+	task = decode[server.Task](t, data)
+	assert.True(t, task.Done)
+	assert.False(t, task.CompletedAt.IsZero())
+
+	_, data = do(t, "PATCH", ts.URL+"/tasks/1", `{"done": false, "priority": ""}`)
+	task = decode[server.Task](t, data)
+	assert.False(t, task.Done)
+	assert.Empty(t, task.Priority)
+
+	// Completing a recurring task schedules its next occurrence.
+	_, _ = do(t, "PATCH", ts.URL+"/tasks/2", `{"done": true}`)
+	require.Len(t, store.list, 3)
+	assert.Equal(t, "Water plants", store.list[2].Task)
+
+	resp, _ = do(t, "PATCH", ts.URL+"/tasks/9", `{"done": true}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = do(t, "PATCH", ts.URL+"/tasks/1", `{"parent": "1"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	assert.Equal(t, []string{
+		"PATCH /tasks/" + id, "PATCH /tasks/1", "PATCH /tasks/1", "PATCH /tasks/2",
+	}, store.actions)
+}
+
+func TestDeleteTask(t *testing.T) {
+	ts, store := newServer(t, "Write report", "Buy milk")
+
+	resp, _ := do(t, "DELETE", ts.URL+"/tasks/1", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Len(t, store.list, 1)
+	assert.Equal(t, "Buy milk", store.list[0].Task)
+
+	resp, _ = do(t, "DELETE", ts.URL+"/tasks/2", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err := http.Post(ts.URL+"/tasks/1", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestRejectCrossSiteRequests(t *testing.T) {
+	ts, store := newServer(t, "Write report")
+	port := ts.Listener.Addr().(*net.TCPAddr).Port
+
+	request := func(method, body string) *http.Request {
+		req, err := http.NewRequest(method, ts.URL+"/tasks", strings.NewReader(body))
+		require.NoError(t, err)
+		return req
+	}
+
+	t.Run("ContentType", func(t *testing.T) {
+		for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "not a type"} {
+			req := request("POST", `{"task": "Buy milk"}`)
+			req.Header.Set("Content-Type", contentType)
+			resp, data := send(t, req)
+			assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode, contentType)
+			assert.Contains(t, string(data), "expected application/json", contentType)
+		}
+
+		req := request("POST", `{"task": "Buy milk"}`)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		resp, _ := send(t, req)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	})
+
+	t.Run("Host", func(t *testing.T) {
+		for _, host := range []string{
+			"evil.example:" + strconv.Itoa(port),
+			"localhost",
+			"127.0.0.1:1",
+		} {
+			req := request("GET", "")
+			req.Host = host
+			resp, data := send(t, req)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, host)
+			assert.Contains(t, string(data), "is not allowed", host)
+		}
+
+		for _, host := range []string{"localhost", "127.0.0.1", "[::1]"} {
+			req := request("GET", "")
+			req.Host = host + ":" + strconv.Itoa(port)
+			resp, _ := send(t, req)
+			assert.Equal(t, http.StatusOK, resp.StatusCode, host)
+		}
+	})
+
+	t.Run("Origin", func(t *testing.T) {
+		for _, origin := range []string{
+			"http://evil.example",
+			"https://localhost:" + strconv.Itoa(port),
+			"http://localhost:1",
+			"null",
+		} {
+			req := request("POST", `{"task": "Cross-site"}`)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Origin", origin)
+			resp, data := send(t, req)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, origin)
+			assert.Contains(t, string(data), "is not allowed", origin)
+		}
+
+		req := request("GET", "")
+		req.Header.Set("Origin", "http://localhost:"+strconv.Itoa(port))
+		resp, _ := send(t, req)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	// Rejected requests change nothing.
+	assert.Equal(t, []string{"POST /tasks"}, store.actions)
+}
@@ -0,0 +1,125 @@
+package todo
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveFile returns the archive path kept next to a todo file, e.g.
+// todo.json -> todo.archive.jsonl.
+func ArchiveFile(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + ".archive.jsonl"
+}
+
+// Archive removes completed items finished before cutoff and returns them
+// in list order. A zero cutoff takes every completed item. A task is only
+// archived together with all of its subtasks: if any of them is open or
+// finished too recently, the whole task stays so no subtask loses its
+// parent.
+func (l *List) Archive(cutoff time.Time) []Item {
+	eligible := func(item Item) bool {
+		return item.Done && (cutoff.IsZero() || item.CompletedAt.Before(cutoff))
+	}
+
+	var archived []Item
+	var kept List
+
+	for i, item := range *l {
+		if !eligible(item) {
+			kept = append(kept, item)
+			continue
+		}
+
+		whole := true
+		for _, d := range l.Descendants(i + 1) {
+			if !eligible((*l)[d-1]) {
+				whole = false
+				break
+			}
+		}
+		if !whole {
+			kept = append(kept, item)
+			continue
+		}
+
+		archived = append(archived, item)
+	}
+
+	if len(archived) > 0 {
+		*l = kept
+	}
+	return archived
+}
+
+// AppendArchive adds items to the end of an archive file, one JSON object
+// per line, creating the file if needed. Existing lines are never
+// rewritten.
+func AppendArchive(filename string, items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadArchive reads every item from an archive file, oldest first. A
+// missing file is an empty archive.
+func ReadArchive(filename string) ([]Item, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []Item
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var item Item
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, n, err)
+		}
+		items = append(items, item)
+	}
+
+	return items, scanner.Err()
+}
@@ -0,0 +1,100 @@
+package todo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestArchiveFile(t *testing.T) {
+	assert.Equal(t, "/tmp/work.archive.jsonl", todo.ArchiveFile("/tmp/work.json"))
+}
+
+func TestListArchive(t *testing.T) {
+	now := time.Now()
+
+	var l todo.List
+	l.Add("Open task")
+	l.Add("Done long ago")
+	l.Add("Done just now")
+	l.Add("Done parent")
+	l.Add("Open subtask")
+	require.NoError(t, l.SetParent(5, 4))
+
+	for _, n := range []int{2, 3, 4} {
+		require.NoError(t, l.Complete(n))
+	}
+	l[1].CompletedAt = now.AddDate(0, 0, -40)
+	l[3].CompletedAt = now.AddDate(0, 0, -40)
+
+	archived := l.Archive(now.AddDate(0, 0, -30))
+	require.Len(t, archived, 1)
+	assert.Equal(t, "Done long ago", archived[0].Task)
+	assert.Equal(t, now.AddDate(0, 0, -40).Unix(), archived[0].CompletedAt.Unix())
+
+	require.Len(t, l, 4, "a parent with open subtasks stays")
+
+	archived = l.Archive(time.Time{})
+	require.Len(t, archived, 1)
+	assert.Equal(t, "Done just now", archived[0].Task)
+
+	assert.Empty(t, l.Archive(time.Time{}))
+	assert.Len(t, l, 3)
+}
+
+func TestListArchiveKeepsSubtreesTogether(t *testing.T) {
+	now := time.Now()
+	old := now.AddDate(0, 0, -40)
+
+	var l todo.List
+	l.Add("Parent")
+	l.Add("Old subtask")
+	l.Add("Recent subtask")
+	require.NoError(t, l.SetParent(2, 1))
+	require.NoError(t, l.SetParent(3, 2))
+
+	for n := range 3 {
+		l[n].Done = true
+		l[n].CompletedAt = old
+	}
+	l[2].CompletedAt = now
+
+	assert.Empty(t, l.Archive(now.AddDate(0, 0, -30)), "a recent grandchild keeps its ancestors")
+	require.Len(t, l, 3)
+
+	archived := l.Archive(time.Time{})
+	require.Len(t, archived, 3, "the whole subtree is archived at once")
+	assert.Equal(t, "Parent", archived[0].Task)
+	assert.Empty(t, l)
+}
+
+func TestAppendAndReadArchive(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.archive.jsonl")
+
+	items, err := todo.ReadArchive(file)
+	require.NoError(t, err)
+	assert.Empty(t, items, "a missing archive is empty")
+
+	var l todo.List
+	first := l.Add("First")
+	second := l.Add("Second")
+
+	require.NoError(t, todo.AppendArchive(file, []todo.Item{first}))
+	require.NoError(t, todo.AppendArchive(file, []todo.Item{second}))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"task":"First"`)
+
+	items, err = todo.ReadArchive(file)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, first.ID, items[0].ID)
+	assert.Equal(t, second.ID, items[1].ID)
+}
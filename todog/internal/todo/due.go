@@ -0,0 +1,105 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format used to read and print due dates.
+const DateLayout = "2006-01-02"
+
+// ParseDue interprets a due date relative to now. It accepts an ISO date
+// (2026-11-01), "today", "tomorrow", a weekday name (friday, fri), or an
+// offset such as +3d or +2w. The result is the start of the matching day.
+func ParseDue(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	today := StartOfDay(now)
+
+	switch s {
+	case "":
+		return time.Time{}, fmt.Errorf("due date cannot be blank")
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if offset, ok := strings.CutPrefix(s, "+"); ok {
+		days, err := ParseDays(offset)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid due date %q: %w", s, err)
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		name := strings.ToLower(wd.String())
+		if s == name || s == name[:3] {
+			ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+			return today.AddDate(0, 0, ahead), nil
+		}
+	}
+
+	due, err := time.ParseInLocation(DateLayout, s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date %q (expected YYYY-MM-DD, today, tomorrow, a weekday, or +Nd)", s)
+	}
+
+	return due, nil
+}
+
+// ParseDays converts a span such as 7d or 2w into a number of days.
+func ParseDays(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid span %q (expected Nd or Nw)", s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid span %q (expected Nd or Nw)", s)
+	}
+
+	switch s[len(s)-1] {
+	case 'd':
+		return n, nil
+	case 'w':
+		return n * 7, nil
+	}
+
+	return 0, fmt.Errorf("invalid span %q (expected Nd or Nw)", s)
+}
+
+// StartOfDay returns midnight at the beginning of t's day.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfWeek returns midnight at the beginning of the Monday of t's week.
+func StartOfWeek(t time.Time) time.Time {
+	day := StartOfDay(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// HasDue reports whether the item has a due date.
+func (i Item) HasDue() bool {
+	return !i.Due.IsZero()
+}
+
+// IsOverdue reports whether an open item's due date is before today.
+func (i Item) IsOverdue(now time.Time) bool {
+	return !i.Done && i.HasDue() && i.Due.Before(StartOfDay(now))
+}
+
+// SetDue sets the due date of the i-th task. A zero time clears it.
+func (l *List) SetDue(i int, due time.Time) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	(*l)[i-1].Due = due
+	return nil
+}
@@ -0,0 +1,75 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestParseDue(t *testing.T) {
+	// Wednesday afternoon
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2026-11-01", "2026-11-01"},
+		{"today", "2026-10-14"},
+		{"Tomorrow", "2026-10-15"},
+		{"friday", "2026-10-16"},
+		{"wed", "2026-10-14"},
+		{"monday", "2026-10-19"},
+		{"+3d", "2026-10-17"},
+		{"+2w", "2026-10-28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			due, err := todo.ParseDue(tt.input, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, due.Format(todo.DateLayout))
+		})
+	}
+
+	for _, input := range []string{"", "someday", "+3", "2026-13-01"} {
+		_, err := todo.ParseDue(input, now)
+		assert.Error(t, err, "expected %q to be rejected", input)
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	days, err := todo.ParseDays("7d")
+	require.NoError(t, err)
+	assert.Equal(t, 7, days)
+
+	days, err = todo.ParseDays("2w")
+	require.NoError(t, err)
+	assert.Equal(t, 14, days)
+
+	_, err = todo.ParseDays("3m")
+	assert.Error(t, err)
+}
+
+func TestIsOverdue(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+
+	var list todo.List
+	list.Add("Yesterday")
+	list.Add("Today")
+	list.Add("No due date")
+
+	require.NoError(t, list.SetDue(1, time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, list.SetDue(2, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)))
+
+	assert.True(t, list[0].IsOverdue(now))
+	assert.False(t, list[1].IsOverdue(now))
+	assert.False(t, list[2].IsOverdue(now))
+
+	require.NoError(t, list.Complete(1))
+	assert.False(t, list[0].IsOverdue(now), "completed tasks are never overdue")
+}
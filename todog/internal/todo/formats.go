@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// csvHeader names the columns written by WriteCSV.
+var csvHeader = []string{
+	"id", "task", "done", "priority", "due", "created_at", "completed_at",
+	"projects", "contexts", "recur", "parent",
+}
+
+// WriteCSV writes items as CSV with a header row. Timestamps use RFC 3339
+// and are left empty when unset; tags are separated by spaces.
+func WriteCSV(w io.Writer, items []Item) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		due := ""
+		if item.HasDue() {
+			due = item.Due.Format(DateLayout)
+		}
+
+		record := []string{
+			item.ID,
+			item.Task,
+			fmt.Sprint(item.Done),
+			item.Priority,
+			due,
+			formatTimestamp(item.CreatedAt),
+			formatTimestamp(item.CompletedAt),
+			strings.Join(item.Projects, " "),
+			strings.Join(item.Contexts, " "),
+			item.Recur,
+			item.Parent,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// WriteMarkdown writes items as a GitHub-style checklist, indenting
+// subtasks beneath their parents.
+func WriteMarkdown(w io.Writer, items []Item) error {
+	order, depths := TreeOrder(items)
+
+	for n, i := range order {
+		status := "[ ]"
+		if items[i].Done {
+			status = "[x]"
+		}
+
+		indent := strings.Repeat("  ", depths[n])
+		if _, err := fmt.Fprintf(w, "%s- %s %s\n", indent, status, items[i].Task); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes items in the same JSON format the list is saved in.
+func WriteJSON(w io.Writer, items []Item) error {
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// ParseChecklistItem reads one Markdown checklist line such as "- [ ] task"
+// or "* [x] task". It reports false for lines that aren't checklist items.
+func ParseChecklistItem(line string) (Item, bool) {
+	line = strings.TrimSpace(line)
+	if len(line) < 2 || !strings.ContainsRune("-*+", rune(line[0])) || line[1] != ' ' {
+		return Item{}, false
+	}
+
+	rest := strings.TrimSpace(line[2:])
+
+	var done bool
+	switch {
+	case strings.HasPrefix(rest, "[ ]"):
+	case strings.HasPrefix(rest, "[x]"), strings.HasPrefix(rest, "[X]"):
+		done = true
+	default:
+		return Item{}, false
+	}
+
+	task := strings.TrimSpace(rest[3:])
+	if task == "" {
+		return Item{}, false
+	}
+
+	item := Item{Task: task, Done: done}
+	item.Projects, item.Contexts = ParseTags(task)
+
+	return item, true
+}
@@ -0,0 +1,81 @@
+package todo_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	var list todo.List
+	list.Add("Release v2")
+	list.Add("Unrelated")
+	list.Add("Write notes")
+	require.NoError(t, list.SetParent(3, 1))
+	require.NoError(t, list.Complete(3))
+
+	var out bytes.Buffer
+	require.NoError(t, todo.WriteMarkdown(&out, list))
+
+	assert.Equal(t, "- [ ] Release v2\n  - [x] Write notes\n- [ ] Unrelated\n", out.String())
+}
+
+func TestWriteCSV(t *testing.T) {
+	var list todo.List
+	list.Add(`Quote "this", please +docs @desk`)
+	require.NoError(t, list.Prioritize(1, "A"))
+
+	var out bytes.Buffer
+	require.NoError(t, todo.WriteCSV(&out, list))
+
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "task", records[0][1])
+	assert.Equal(t, list[0].ID, records[1][0])
+	assert.Equal(t, `Quote "this", please +docs @desk`, records[1][1])
+	assert.Equal(t, "false", records[1][2])
+	assert.Equal(t, "A", records[1][3])
+	assert.Empty(t, records[1][4], "no due date")
+	assert.Empty(t, records[1][6], "not completed")
+	assert.Equal(t, "+docs", records[1][7])
+	assert.Equal(t, "@desk", records[1][8])
+}
+
+func TestWriteJSON(t *testing.T) {
+	var list todo.List
+	list.Add("Task 1")
+
+	var out bytes.Buffer
+	require.NoError(t, todo.WriteJSON(&out, list))
+
+	var decoded todo.List
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, list[0].ID, decoded[0].ID)
+}
+
+func TestParseChecklistItem(t *testing.T) {
+	item, ok := todo.ParseChecklistItem("- [ ] Review PR +release")
+	require.True(t, ok)
+	assert.Equal(t, "Review PR +release", item.Task)
+	assert.False(t, item.Done)
+	assert.Equal(t, []string{"+release"}, item.Projects)
+
+	item, ok = todo.ParseChecklistItem("  * [X] Merge it")
+	require.True(t, ok)
+	assert.Equal(t, "Merge it", item.Task)
+	assert.True(t, item.Done)
+
+	for _, line := range []string{"## Checklist", "- plain bullet", "- [ ]", "-[ ] no space", "[ ] no bullet"} {
+		_, ok := todo.ParseChecklistItem(line)
+		assert.False(t, ok, "expected %q to be ignored", line)
+	}
+}
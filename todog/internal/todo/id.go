@@ -0,0 +1,141 @@
+package todo
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// idAlphabet excludes digits so an ID can never be mistaken for a position,
+// and drops i, l, and o, which are easily confused when read aloud.
+const idAlphabet = "abcdefghjkmnpqrstuvwxyz"
+
+const idLength = 4
+
+// newID returns a short random ID not already used in the list.
+func (l *List) newID() string {
+	for {
+		var b strings.Builder
+		for range idLength {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(idAlphabet))))
+			if err != nil {
+				panic(fmt.Sprintf("todo: cannot generate ID: %v", err))
+			}
+			b.WriteByte(idAlphabet[n.Int64()])
+		}
+
+		id := b.String()
+		if l.indexOfID(id) < 0 {
+			return id
+		}
+	}
+}
+
+// derivedID returns an ID computed from the item's content that isn't in
+// taken. It lets items loaded from files written before IDs existed show
+// the same ID on every read until the list is saved.
+func derivedID(item Item, taken map[string]bool) string {
+	for salt := 0; ; salt++ {
+		sum := sha256.Sum256(fmt.Appendf(nil, "%d|%s|%d", item.CreatedAt.UnixNano(), item.Task, salt))
+
+		var b strings.Builder
+		for _, c := range sum[:idLength] {
+			b.WriteByte(idAlphabet[int(c)%len(idAlphabet)])
+		}
+
+		if id := b.String(); !taken[id] {
+			return id
+		}
+	}
+}
+
+// validID reports whether id has the form newID produces.
+func validID(id string) bool {
+	if len(id) != idLength {
+		return false
+	}
+	for _, c := range id {
+		if !strings.ContainsRune(idAlphabet, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexOfID returns the 0-based index of the item with the given ID, or -1.
+func (l *List) indexOfID(id string) int {
+	for i, item := range *l {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Resolve turns a task reference into a 1-based position. A reference is
+// either a position as shown by "todog list" or an item's stable ID.
+func (l *List) Resolve(ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n <= 0 || n > len(*l) {
+			return 0, notFound(n)
+		}
+		return n, nil
+	}
+
+	if i := l.indexOfID(strings.ToLower(ref)); i >= 0 {
+		return i + 1, nil
+	}
+
+	return 0, &NotFoundError{Ref: strconv.Quote(ref)}
+}
+
+// ResolveAll resolves several task references at once, expanding position
+// ranges such as 5-8. Duplicates are dropped; the result keeps the order in
+// which references first appear. It fails without partial results if any
+// reference is invalid: a malformed range is a *RangeError, and a range
+// reaching past the list reports its first missing position as not found.
+func (l *List) ResolveAll(refs []string) ([]int, error) {
+	var nums []int
+	seen := make(map[int]bool)
+
+	add := func(n int) {
+		if !seen[n] {
+			seen[n] = true
+			nums = append(nums, n)
+		}
+	}
+
+	for _, ref := range refs {
+		if from, to, ok := strings.Cut(ref, "-"); ok {
+			start, err1 := strconv.Atoi(from)
+			end, err2 := strconv.Atoi(to)
+			if err1 != nil || err2 != nil || start > end {
+				return nil, &RangeError{Range: ref}
+			}
+			if start <= 0 {
+				return nil, notFound(start)
+			}
+			if end > len(*l) {
+				return nil, notFound(max(start, len(*l)+1))
+			}
+
+			for n := start; n <= end; n++ {
+				add(n)
+			}
+			continue
+		}
+
+		n, err := l.Resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		add(n)
+	}
+
+	return nums, nil
+}
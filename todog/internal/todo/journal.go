@@ -0,0 +1,205 @@
+package todo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxOperations caps how many operations a journal remembers.
+const maxOperations = 100
+
+// Operation records one change to a list as the run of items it replaced:
+// starting at index Start, the items in Before were replaced by those in
+// After, and everything around them was left alone. Storing only that run
+// keeps the journal small however long the list grows. BeforeSum and
+// AfterSum fingerprint the whole list on either side of the change, so
+// undo and redo can tell when it has been changed since.
+//
+// A Final operation changed more than the list, so reverting the list alone
+// would leave things inconsistent; undo stops at it.
+type Operation struct {
+	Command   string    `json:"command"`
+	Time      time.Time `json:"time"`
+	Start     int       `json:"start"`
+	Before    List      `json:"before"`
+	After     List      `json:"after"`
+	BeforeSum string    `json:"before_sum"`
+	AfterSum  string    `json:"after_sum"`
+	Final     bool      `json:"final,omitempty"`
+}
+
+// Journal is the undo/redo history of a list.
+type Journal struct {
+	Operations []Operation `json:"operations"`
+	// Undone counts the operations at the end of Operations that have been
+	// undone and can be redone.
+	Undone int `json:"undone"`
+}
+
+// JournalFile returns the journal path kept next to a todo file, e.g.
+// todo.json -> todo.journal.json.
+func JournalFile(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + ".journal" + ext
+}
+
+// Record appends an operation, discarding anything that was undone since
+// redoing it would no longer make sense. Operations that change nothing are
+// not recorded.
+func (j *Journal) Record(command string, before, after List) {
+	j.record(command, before, after, false)
+}
+
+// RecordFinal is Record for an operation that can't be undone, such as
+// archiving, which also moves tasks to another file.
+func (j *Journal) RecordFinal(command string, before, after List) {
+	j.record(command, before, after, true)
+}
+
+func (j *Journal) record(command string, before, after List, final bool) {
+	if sameList(before, after) {
+		return
+	}
+
+	j.Operations = j.Operations[:len(j.Operations)-j.Undone]
+	j.Undone = 0
+
+	start, removed, added := diffLists(before, after)
+	j.Operations = append(j.Operations, Operation{
+		Command:   command,
+		Time:      time.Now(),
+		Start:     start,
+		Before:    removed,
+		After:     added,
+		BeforeSum: checksum(before),
+		AfterSum:  checksum(after),
+		Final:     final,
+	})
+
+	if over := len(j.Operations) - maxOperations; over > 0 {
+		j.Operations = j.Operations[over:]
+	}
+}
+
+// Undo reverts the most recent operation that hasn't been undone, returning
+// the list as it was before that operation. It refuses if current no longer
+// matches the state the operation left behind.
+func (j *Journal) Undo(current List) (List, Operation, error) {
+	if j.Undone >= len(j.Operations) {
+		return nil, Operation{}, errors.New("nothing to undo")
+	}
+
+	op := j.Operations[len(j.Operations)-1-j.Undone]
+	if op.Final {
+		return nil, Operation{}, fmt.Errorf("%q can't be undone", op.Command)
+	}
+	if checksum(current) != op.AfterSum {
+		return nil, Operation{}, fmt.Errorf("the list has changed since %q; refusing to undo", op.Command)
+	}
+
+	j.Undone++
+	return splice(current, op.Start, len(op.After), op.Before), op, nil
+}
+
+// Redo reapplies the most recently undone operation, returning the list as
+// it was after that operation.
+func (j *Journal) Redo(current List) (List, Operation, error) {
+	if j.Undone == 0 {
+		return nil, Operation{}, errors.New("nothing to redo")
+	}
+
+	op := j.Operations[len(j.Operations)-j.Undone]
+	if checksum(current) != op.BeforeSum {
+		return nil, Operation{}, fmt.Errorf("the list has changed since undoing %q; refusing to redo", op.Command)
+	}
+
+	j.Undone--
+	return splice(current, op.Start, len(op.Before), op.After), op, nil
+}
+
+// Save writes the journal to a file in JSON format.
+func (j *Journal) Save(filename string) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, data)
+}
+
+// Get reads the journal from a JSON file, if it exists.
+func (j *Journal) Get(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, j)
+}
+
+// diffLists finds the run of items that differs between before and after,
+// returning where it starts and its contents on either side.
+func diffLists(before, after List) (start int, removed, added List) {
+	a, b := encodeItems(before), encodeItems(after)
+
+	for start < len(a) && start < len(b) && bytes.Equal(a[start], b[start]) {
+		start++
+	}
+
+	end := 0
+	for end < len(a)-start && end < len(b)-start && bytes.Equal(a[len(a)-1-end], b[len(b)-1-end]) {
+		end++
+	}
+
+	return start, slices.Clone(before[start : len(before)-end]), slices.Clone(after[start : len(after)-end])
+}
+
+// splice returns a copy of list with the n items at start replaced by items.
+func splice(list List, start, n int, items List) List {
+	spliced := make(List, 0, len(list)-n+len(items))
+	spliced = append(spliced, list[:start]...)
+	spliced = append(spliced, items...)
+	return append(spliced, list[start+n:]...)
+}
+
+// checksum fingerprints a list by the way its items would be saved.
+func checksum(l List) string {
+	h := sha256.New()
+	for _, item := range encodeItems(l) {
+		h.Write(item)
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// encodeItems returns each item of l as it would be saved.
+func encodeItems(l List) [][]byte {
+	encoded := make([][]byte, len(l))
+	for i, item := range l {
+		encoded[i], _ = json.Marshal(item)
+	}
+	return encoded
+}
+
+// sameList reports whether two lists would be saved identically.
+func sameList(a, b List) bool {
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(da, db)
+}
@@ -0,0 +1,135 @@
+package todo_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestJournalUndoRedo(t *testing.T) {
+	var journal todo.Journal
+
+	var before todo.List
+	before.Add("Task 1")
+
+	after := append(todo.List{}, before...)
+	after.Add("Task 2")
+	journal.Record("add Task 2", before, after)
+
+	restored, op, err := journal.Undo(after)
+	require.NoError(t, err)
+	assert.Equal(t, "add Task 2", op.Command)
+	assert.Len(t, restored, 1)
+
+	_, _, err = journal.Undo(restored)
+	assert.EqualError(t, err, "nothing to undo")
+
+	reapplied, _, err := journal.Redo(restored)
+	require.NoError(t, err)
+	assert.Len(t, reapplied, 2)
+
+	_, _, err = journal.Redo(reapplied)
+	assert.EqualError(t, err, "nothing to redo")
+}
+
+func TestJournalRefusesStaleUndo(t *testing.T) {
+	var journal todo.Journal
+
+	var before, after todo.List
+	after.Add("Task 1")
+	journal.Record("add Task 1", before, after)
+
+	changed := append(todo.List{}, after...)
+	changed.Add("Added outside the journal")
+
+	_, _, err := journal.Undo(changed)
+	assert.Error(t, err)
+}
+
+func TestJournalRecordDiscardsRedo(t *testing.T) {
+	var journal todo.Journal
+
+	var l0, l1, l2 todo.List
+	l1.Add("Task 1")
+	journal.Record("add Task 1", l0, l1)
+
+	_, _, err := journal.Undo(l1)
+	require.NoError(t, err)
+
+	l2.Add("Task 2")
+	journal.Record("add Task 2", l0, l2)
+
+	assert.Len(t, journal.Operations, 1)
+	assert.Zero(t, journal.Undone)
+	_, _, err = journal.Redo(l2)
+	assert.Error(t, err)
+}
+
+func TestJournalSaveGet(t *testing.T) {
+	filename := todo.JournalFile(filepath.Join(t.TempDir(), "todo.json"))
+	assert.Equal(t, "todo.journal.json", filepath.Base(filename))
+
+	var journal todo.Journal
+	var after todo.List
+	after.Add("Task 1")
+	journal.Record("add Task 1", nil, after)
+	require.NoError(t, journal.Save(filename))
+
+	var loaded todo.Journal
+	require.NoError(t, loaded.Get(filename))
+	require.Len(t, loaded.Operations, 1)
+	assert.Equal(t, "add Task 1", loaded.Operations[0].Command)
+}
+
+func TestJournalRecordsOnlyChangedItems(t *testing.T) {
+	var journal todo.Journal
+
+	var before todo.List
+	for n := range 50 {
+		before.Add(fmt.Sprintf("Task %d", n+1))
+	}
+
+	after := append(todo.List{}, before...)
+	after[24].Task = "Task 25, reworded"
+	journal.Record("edit 25", before, after)
+
+	op := journal.Operations[0]
+	assert.Equal(t, 24, op.Start)
+	require.Len(t, op.Before, 1)
+	require.Len(t, op.After, 1)
+	assert.Equal(t, "Task 25", op.Before[0].Task)
+
+	restored, _, err := journal.Undo(after)
+	require.NoError(t, err)
+	assert.Equal(t, before, restored)
+
+	reapplied, _, err := journal.Redo(restored)
+	require.NoError(t, err)
+	assert.Equal(t, after, reapplied)
+
+	// A change outside the recorded run still counts as a change.
+	_, _, err = journal.Undo(reapplied)
+	require.NoError(t, err)
+	changed := append(todo.List{}, before...)
+	require.NoError(t, changed.Complete(1))
+	_, _, err = journal.Redo(changed)
+	assert.Error(t, err)
+}
+
+func TestJournalStopsAtFinalOperation(t *testing.T) {
+	var journal todo.Journal
+
+	var l0, l1, l2 todo.List
+	l1.Add("Task 1")
+	journal.Record("add Task 1", l0, l1)
+	journal.RecordFinal("archive", l1, l2)
+
+	_, _, err := journal.Undo(l2)
+	assert.EqualError(t, err, `"archive" can't be undone`)
+	assert.Zero(t, journal.Undone)
+}
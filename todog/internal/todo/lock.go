@@ -0,0 +1,27 @@
+package todo
+
+import "os"
+
+// Lock takes an exclusive advisory lock guarding filename, blocking until it
+// is available. Hold it across a Get–modify–Save cycle so that concurrent
+// invocations don't overwrite each other's changes. The lock lives in a
+// separate "<filename>.lock" file because Save replaces the list file itself.
+func Lock(filename string) (unlock func() error, err error) {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() error {
+		if err := unlockFile(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
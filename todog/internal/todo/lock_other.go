@@ -0,0 +1,41 @@
+//go:build !unix
+
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Platforms without flock fall back to a marker file created exclusively
+// next to the lock file, polling until the current holder removes it. A
+// process that dies while holding the lock leaves the marker behind, so
+// waiting gives up after lockTimeout rather than hanging forever.
+
+const lockTimeout = 30 * time.Second
+
+func lockFile(f *os.File) error {
+	marker := f.Name() + ".held"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		m, err := os.OpenFile(marker, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return m.Close()
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the lock on %s; if no other todog is running, delete %s",
+				f.Name(), marker)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func unlockFile(f *os.File) error {
+	return os.Remove(f.Name() + ".held")
+}
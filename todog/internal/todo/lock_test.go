@@ -0,0 +1,69 @@
+package todo_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestLockSerializesUpdates(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	const workers = 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for n := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := todo.Lock(filename)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock()
+
+			var list todo.List
+			if err := list.Get(filename); err != nil {
+				errs <- err
+				return
+			}
+			list.Add(fmt.Sprintf("task %d", n))
+			errs <- list.Save(filename)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var list todo.List
+	require.NoError(t, list.Get(filename))
+	assert.Len(t, list, workers, "no concurrent update should be lost")
+}
+
+func TestSaveLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+
+	var list todo.List
+	list.Add("Task 1")
+	require.NoError(t, list.Save(filename))
+	require.NoError(t, list.Save(filename))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "todo.json", entries[0].Name())
+}
@@ -0,0 +1,90 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Note is a timestamped comment on a task.
+type Note struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddNote appends a note to the i-th task.
+func (l *List) AddNote(i int, text string, now time.Time) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errors.New("note cannot be empty")
+	}
+
+	(*l)[i-1].Notes = append((*l)[i-1].Notes, Note{Text: text, CreatedAt: now})
+	return nil
+}
+
+// opaqueSchemes are URL schemes written without "//", e.g. mailto:me@x.org.
+// Any other scheme needs "//" so that file names such as draft:v2.txt, which
+// are valid on most systems, stay paths.
+var opaqueSchemes = []string{"mailto", "tel", "sms", "urn", "news", "data", "magnet", "geo", "xmpp"}
+
+// IsURL reports whether an attachment is a URL rather than a file path.
+func IsURL(ref string) bool {
+	scheme, rest, ok := strings.Cut(ref, ":")
+	if !ok || len(scheme) < 2 || rest == "" {
+		// A one-letter scheme is a Windows drive, e.g. C:\notes.txt.
+		return false
+	}
+	for i, r := range scheme {
+		letter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+		if i == 0 && !letter || !(letter || r >= '0' && r <= '9' || strings.ContainsRune("+-.", r)) {
+			return false
+		}
+	}
+
+	if strings.HasPrefix(rest, "//") {
+		return len(rest) > 2
+	}
+	return slices.Contains(opaqueSchemes, strings.ToLower(scheme))
+}
+
+// Attach links a file path or URL to the i-th task. Attaching the same
+// reference twice is an error.
+func (l *List) Attach(i int, ref string) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return errors.New("attachment cannot be empty")
+	}
+	if slices.Contains((*l)[i-1].Attachments, ref) {
+		return fmt.Errorf("item %d already has attachment %s", i, ref)
+	}
+
+	(*l)[i-1].Attachments = append((*l)[i-1].Attachments, ref)
+	return nil
+}
+
+// Detach removes an attachment from the i-th task.
+func (l *List) Detach(i int, ref string) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	attachments := (*l)[i-1].Attachments
+	n := slices.Index(attachments, ref)
+	if n < 0 {
+		return fmt.Errorf("item %d has no attachment %s", i, ref)
+	}
+
+	(*l)[i-1].Attachments = slices.Delete(attachments, n, n+1)
+	return nil
+}
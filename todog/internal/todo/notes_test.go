@@ -0,0 +1,63 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestListAddNote(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	var l todo.List
+	l.Add("Fix bug")
+
+	require.NoError(t, l.AddNote(1, "  Happens on empty files ", now))
+	require.NoError(t, l.AddNote(1, "Fixed in parser", now.Add(time.Hour)))
+	assert.Equal(t, []todo.Note{
+		{Text: "Happens on empty files", CreatedAt: now},
+		{Text: "Fixed in parser", CreatedAt: now.Add(time.Hour)},
+	}, l[0].Notes)
+
+	assert.EqualError(t, l.AddNote(1, " ", now), "note cannot be empty")
+	assert.Error(t, l.AddNote(2, "Missing", now))
+}
+
+func TestListAttach(t *testing.T) {
+	var l todo.List
+	l.Add("Write report")
+
+	require.NoError(t, l.Attach(1, "/home/me/report.md"))
+	require.NoError(t, l.Attach(1, "https://example.com/spec"))
+	assert.ErrorContains(t, l.Attach(1, "/home/me/report.md"), "already has attachment")
+	assert.Equal(t, []string{"/home/me/report.md", "https://example.com/spec"}, l[0].Attachments)
+
+	require.NoError(t, l.Detach(1, "/home/me/report.md"))
+	assert.Equal(t, []string{"https://example.com/spec"}, l[0].Attachments)
+	assert.ErrorContains(t, l.Detach(1, "/home/me/report.md"), "has no attachment")
+	assert.Error(t, l.Attach(2, "/tmp/x"))
+}
+
+func TestIsURL(t *testing.T) {
+	for ref, want := range map[string]bool{
+		"https://example.com":   true,
+		"mailto:me@example.com": true,
+		"MAILTO:me@example.com": true,
+		"tel:+1-555-0100":       true,
+		"file:///tmp/notes.txt": true,
+		"/home/me/notes.txt":    false,
+		"notes.txt":             false,
+		`C:\notes.txt`:          false,
+		"a b:c":                 false,
+		"draft:v2.txt":          false,
+		"notes:todo.md":         false,
+		"https://":              false,
+		"2fa:codes.txt":         false,
+	} {
+		assert.Equal(t, want, todo.IsURL(ref), ref)
+	}
+}
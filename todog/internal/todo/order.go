@@ -0,0 +1,58 @@
+package todo
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Move puts the i-th task at position to, shifting the tasks in between.
+// Subtasks are linked by ID, so they stay nested under their parent
+// wherever it goes.
+func (l *List) Move(i, to int) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+	if to <= 0 || to > len(*l) {
+		return &RangeError{Range: strconv.Itoa(to), Max: len(*l)}
+	}
+
+	item := (*l)[i-1]
+	*l = slices.Delete(*l, i-1, i)
+	*l = slices.Insert(*l, to-1, item)
+	return nil
+}
+
+// Sort permanently reorders the list by a field: "created" puts the oldest
+// tasks first, "text" sorts alphabetically ignoring case, and "done" puts
+// open tasks before completed ones. Ties keep their current order.
+func (l *List) Sort(by string) error {
+	var cmp func(a, b Item) int
+
+	switch by {
+	case "created":
+		cmp = func(a, b Item) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	case "text":
+		cmp = func(a, b Item) int {
+			return strings.Compare(strings.ToLower(a.Task), strings.ToLower(b.Task))
+		}
+	case "done":
+		cmp = func(a, b Item) int {
+			switch {
+			case !a.Done && b.Done:
+				return -1
+			case a.Done && !b.Done:
+				return 1
+			}
+			return 0
+		}
+	default:
+		return fmt.Errorf("invalid sort field %q (expected created, text, or done)", by)
+	}
+
+	slices.SortStableFunc(*l, cmp)
+	return nil
+}
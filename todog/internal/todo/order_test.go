@@ -0,0 +1,66 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func taskTexts(l todo.List) []string {
+	var names []string
+	for _, item := range l {
+		names = append(names, item.Task)
+	}
+	return names
+}
+
+func TestListMove(t *testing.T) {
+	var l todo.List
+	for _, task := range []string{"A", "B", "C", "D"} {
+		l.Add(task)
+	}
+	require.NoError(t, l.SetParent(4, 1))
+
+	require.NoError(t, l.Move(3, 1))
+	assert.Equal(t, []string{"C", "A", "B", "D"}, taskTexts(l))
+
+	require.NoError(t, l.Move(1, 4))
+	assert.Equal(t, []string{"A", "B", "D", "C"}, taskTexts(l))
+
+	// The subtask keeps its parent after moving.
+	require.NoError(t, l.Move(3, 1))
+	assert.Equal(t, l[1].ID, l[0].Parent)
+
+	assert.Error(t, l.Move(5, 1))
+	assert.EqualError(t, l.Move(1, 5), "position 5 is out of range (1-4)")
+
+	var rangeErr *todo.RangeError
+	assert.ErrorAs(t, l.Move(1, 0), &rangeErr)
+}
+
+func TestListSort(t *testing.T) {
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	var l todo.List
+	for i, task := range []string{"banana", "Apple", "cherry", "apricot"} {
+		l.Add(task)
+		l[i].CreatedAt = base.AddDate(0, 0, 3-i)
+	}
+	require.NoError(t, l.Complete(2))
+
+	require.NoError(t, l.Sort("text"))
+	assert.Equal(t, []string{"Apple", "apricot", "banana", "cherry"}, taskTexts(l))
+
+	require.NoError(t, l.Sort("created"))
+	assert.Equal(t, []string{"apricot", "cherry", "Apple", "banana"}, taskTexts(l))
+
+	// Open tasks come first; ties keep their order.
+	require.NoError(t, l.Sort("done"))
+	assert.Equal(t, []string{"apricot", "cherry", "banana", "Apple"}, taskTexts(l))
+
+	assert.EqualError(t, l.Sort("priority"), `invalid sort field "priority" (expected created, text, or done)`)
+}
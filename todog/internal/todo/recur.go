@@ -0,0 +1,111 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrenceUnits maps the units accepted in "every N <unit>" rules to the
+// single-interval names rules are normalized to.
+var recurrenceUnits = map[string]string{
+	"day":   "daily",
+	"week":  "weekly",
+	"month": "monthly",
+	"year":  "yearly",
+}
+
+// ParseRecurrence normalizes a recurrence rule. It accepts daily, weekly,
+// monthly, and yearly, as well as "every N days" (or weeks, months, years),
+// with or without the leading "every". An empty string or "none" means the
+// task does not repeat.
+func ParseRecurrence(s string) (string, error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if s == "" || s == "none" {
+		return "", nil
+	}
+
+	for _, simple := range recurrenceUnits {
+		if s == simple {
+			return s, nil
+		}
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(s, "every "))
+
+	n := 1
+	if len(fields) == 2 {
+		var err error
+		if n, err = strconv.Atoi(fields[0]); err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid recurrence %q", s)
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) != 1 {
+		return "", fmt.Errorf("invalid recurrence %q (expected daily, weekly, monthly, yearly, or \"every N days\")", s)
+	}
+
+	unit := strings.TrimSuffix(fields[0], "s")
+	simple, ok := recurrenceUnits[unit]
+	if !ok {
+		return "", fmt.Errorf("invalid recurrence %q (expected daily, weekly, monthly, yearly, or \"every N days\")", s)
+	}
+
+	if n == 1 {
+		return simple, nil
+	}
+	return fmt.Sprintf("every %d %ss", n, unit), nil
+}
+
+// advance moves t forward by one interval of a normalized rule.
+func advance(rule string, t time.Time) time.Time {
+	n := 1
+	unit := rule
+
+	if rest, ok := strings.CutPrefix(rule, "every "); ok {
+		count, name, _ := strings.Cut(rest, " ")
+		n, _ = strconv.Atoi(count)
+		unit = recurrenceUnits[strings.TrimSuffix(name, "s")]
+	}
+
+	switch unit {
+	case "daily":
+		return t.AddDate(0, 0, n)
+	case "weekly":
+		return t.AddDate(0, 0, 7*n)
+	case "monthly":
+		return t.AddDate(0, n, 0)
+	case "yearly":
+		return t.AddDate(n, 0, 0)
+	}
+
+	return t
+}
+
+// NextDue computes the due date of the occurrence that follows item when it
+// is completed at now. The schedule continues from the item's due date
+// (or from today if it has none), skipping any dates that have already
+// passed so that finishing late doesn't spawn an overdue task.
+func NextDue(item Item, now time.Time) time.Time {
+	today := StartOfDay(now)
+
+	next := today
+	if item.HasDue() {
+		next = item.Due
+	}
+
+	for {
+		following := advance(item.Recur, next)
+		if !following.After(next) {
+			// Guard against a malformed rule that would never advance.
+			return following
+		}
+		next = following
+
+		if next.After(today) {
+			return next
+		}
+	}
+}
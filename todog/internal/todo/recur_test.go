@@ -0,0 +1,92 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Weekly", "weekly"},
+		{"every day", "daily"},
+		{"every 2 days", "every 2 days"},
+		{"3 weeks", "every 3 weeks"},
+		{"every 1 month", "monthly"},
+		{"none", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rule, err := todo.ParseRecurrence(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rule)
+		})
+	}
+
+	for _, input := range []string{"fortnightly", "every 0 days", "every two weeks", "every 2 fortnights"} {
+		_, err := todo.ParseRecurrence(input)
+		assert.Error(t, err, "expected %q to be rejected", input)
+	}
+}
+
+func TestNextDue(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	day := func(m time.Month, d int) time.Time {
+		return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		item     todo.Item
+		expected time.Time
+	}{
+		{"WeeklyFromDue", todo.Item{Recur: "weekly", Due: day(10, 14)}, day(10, 21)},
+		{"MonthlyFromDue", todo.Item{Recur: "monthly", Due: day(10, 20)}, day(11, 20)},
+		{"WithoutDueStartsToday", todo.Item{Recur: "every 2 days"}, day(10, 16)},
+		{"SkipsMissedOccurrences", todo.Item{Recur: "weekly", Due: day(9, 30)}, day(10, 21)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, todo.NextDue(tt.item, now))
+		})
+	}
+}
+
+func TestCompleteRecurring(t *testing.T) {
+	var list todo.List
+	list.Add("Rotate on-call +ops")
+	require.NoError(t, list.SetRecurrence(1, "weekly"))
+	require.NoError(t, list.Prioritize(1, "B"))
+
+	require.NoError(t, list.Complete(1))
+
+	require.Len(t, list, 2)
+	next := list[1]
+	assert.Equal(t, "Rotate on-call +ops", next.Task)
+	assert.False(t, next.Done)
+	assert.Equal(t, "weekly", next.Recur)
+	assert.Equal(t, "B", next.Priority)
+	assert.Equal(t, []string{"+ops"}, next.Projects)
+	assert.NotEqual(t, list[0].ID, next.ID)
+	assert.True(t, next.Due.After(time.Now()))
+
+	// Completing an already completed occurrence must not spawn another.
+	require.NoError(t, list.Complete(1))
+	assert.Len(t, list, 2)
+
+	// Nor must completing it again after reopening it.
+	assert.Equal(t, next.ID, list[0].Spawned)
+	require.NoError(t, list.Reopen(1))
+	require.NoError(t, list.Complete(1))
+	assert.Len(t, list, 2)
+	assert.Empty(t, list[1].Spawned)
+}
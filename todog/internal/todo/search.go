@@ -0,0 +1,97 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Matcher looks for a search pattern in task text. It returns the byte
+// ranges of the matched text as [start, end) pairs, or nil if the text
+// doesn't match.
+type Matcher func(text string) [][]int
+
+// SubstringMatcher matches text containing pattern, ignoring case.
+func SubstringMatcher(pattern string) (Matcher, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, errors.New("search pattern cannot be blank")
+	}
+
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+	return regexpMatcher(re), nil
+}
+
+// RegexMatcher matches text against a regular expression in Go syntax.
+// Matching is case-sensitive unless the pattern starts with (?i).
+func RegexMatcher(pattern string) (Matcher, error) {
+	if pattern == "" {
+		return nil, errors.New("search pattern cannot be blank")
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return regexpMatcher(re), nil
+}
+
+func regexpMatcher(re *regexp.Regexp) Matcher {
+	return func(text string) [][]int {
+		var ranges [][]int
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			if m[0] < m[1] {
+				ranges = append(ranges, m)
+			}
+		}
+		// A pattern like "x*" matches everywhere, but only as empty strings.
+		if ranges == nil && re.MatchString(text) {
+			return [][]int{}
+		}
+		return ranges
+	}
+}
+
+// FuzzyMatcher matches text containing the characters of pattern in order,
+// though not necessarily next to each other, ignoring case and spaces in
+// the pattern. "wrtrpt" matches "Write report".
+func FuzzyMatcher(pattern string) (Matcher, error) {
+	var want []rune
+	for _, r := range pattern {
+		if !unicode.IsSpace(r) {
+			want = append(want, unicode.ToLower(r))
+		}
+	}
+	if len(want) == 0 {
+		return nil, errors.New("search pattern cannot be blank")
+	}
+
+	return func(text string) [][]int {
+		var ranges [][]int
+		next := 0
+
+		for i, r := range text {
+			if next == len(want) {
+				break
+			}
+			if unicode.ToLower(r) != want[next] {
+				continue
+			}
+			next++
+
+			end := i + utf8.RuneLen(r)
+			if n := len(ranges); n > 0 && ranges[n-1][1] == i {
+				ranges[n-1][1] = end // extend a run of adjacent matches
+			} else {
+				ranges = append(ranges, []int{i, end})
+			}
+		}
+
+		if next < len(want) {
+			return nil
+		}
+		return ranges
+	}, nil
+}
@@ -0,0 +1,61 @@
+package todo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestSubstringMatcher(t *testing.T) {
+	match, err := todo.SubstringMatcher("report")
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]int{{6, 12}}, match("Write REPORT for Q3"))
+	assert.Equal(t, [][]int{{0, 6}, {7, 13}}, match("report report"))
+	assert.Nil(t, match("Review PR"))
+
+	// Regex metacharacters are matched literally.
+	match, err = todo.SubstringMatcher("v1.2")
+	require.NoError(t, err)
+	assert.NotNil(t, match("Ship v1.2"))
+	assert.Nil(t, match("Ship v112"))
+
+	_, err = todo.SubstringMatcher("  ")
+	assert.Error(t, err)
+}
+
+func TestRegexMatcher(t *testing.T) {
+	match, err := todo.RegexMatcher(`^Call \w+`)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]int{{0, 8}}, match("Call Bob @phone"))
+	assert.Nil(t, match("call Bob"), "regex matching is case-sensitive")
+	assert.Nil(t, match("Recall Bob"))
+
+	match, err = todo.RegexMatcher(`x*`)
+	require.NoError(t, err)
+	assert.NotNil(t, match("anything"), "empty matches still count")
+
+	_, err = todo.RegexMatcher(`(`)
+	assert.Error(t, err)
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	match, err := todo.FuzzyMatcher("wrt rpt")
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]int{{0, 2}, {3, 4}, {6, 7}, {8, 9}, {11, 12}}, match("Write report"))
+	assert.Nil(t, match("Review PR"))
+	assert.Nil(t, match("tw"), "characters must appear in order")
+
+	// Multi-byte runes are matched and reported whole.
+	match, err = todo.FuzzyMatcher("ÇA")
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{4, 7}}, match("Français"))
+
+	_, err = todo.FuzzyMatcher(" ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Period is the length of the buckets that Stats counts activity in.
+type Period string
+
+const (
+	Day  Period = "day"
+	Week Period = "week"
+)
+
+// ParsePeriod accepts "day" or "week".
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(s); p {
+	case Day, Week:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid period %q (expected day or week)", s)
+}
+
+// start returns the beginning of the period containing t. Weeks start on
+// Monday.
+func (p Period) start(t time.Time) time.Time {
+	if p == Week {
+		return StartOfWeek(t)
+	}
+	return StartOfDay(t)
+}
+
+func (p Period) next(t time.Time) time.Time {
+	if p == Week {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// PeriodStats counts activity in one period.
+type PeriodStats struct {
+	Start     time.Time `json:"start"`
+	Created   int       `json:"created"`
+	Completed int       `json:"completed"`
+	// Open is the backlog of unfinished tasks at the end of the period.
+	Open int `json:"open"`
+}
+
+// Stats summarises a list and its archive.
+type Stats struct {
+	Open      int `json:"open"`
+	Completed int `json:"completed"`
+	Archived  int `json:"archived"`
+	// MedianTimeToComplete is the median time from creation to completion
+	// of every completed task, or zero if there are none.
+	MedianTimeToComplete time.Duration `json:"-"`
+	// CurrentStreak counts consecutive days up to today with at least one
+	// completion. A streak isn't broken until a whole day passes without
+	// one, so it may end yesterday.
+	CurrentStreak int           `json:"current_streak_days"`
+	LongestStreak int           `json:"longest_streak_days"`
+	Period        Period        `json:"period"`
+	Periods       []PeriodStats `json:"periods"`
+}
+
+// MarshalJSON reports the median time to complete in seconds.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	type plain Stats
+	return json.Marshal(struct {
+		plain
+		MedianSeconds float64 `json:"median_time_to_complete_seconds"`
+	}{plain(s), s.MedianTimeToComplete.Seconds()})
+}
+
+// ComputeStats summarises items and archived items as of now, counting
+// activity in the last n periods, oldest first.
+func ComputeStats(items, archived []Item, now time.Time, period Period, n int) Stats {
+	stats := Stats{Archived: len(archived), Period: period}
+
+	for _, item := range items {
+		if item.Done {
+			stats.Completed++
+		} else {
+			stats.Open++
+		}
+	}
+
+	all := append(append([]Item(nil), items...), archived...)
+
+	var durations []time.Duration
+	completedDays := make(map[time.Time]bool)
+	for _, item := range all {
+		if !item.Done || item.CompletedAt.IsZero() {
+			continue
+		}
+		completedDays[StartOfDay(item.CompletedAt.In(now.Location()))] = true
+		if d := item.CompletedAt.Sub(item.CreatedAt); !item.CreatedAt.IsZero() && d >= 0 {
+			durations = append(durations, d)
+		}
+	}
+
+	stats.MedianTimeToComplete = median(durations)
+	stats.CurrentStreak, stats.LongestStreak = streaks(completedDays, now)
+
+	start := period.start(now)
+	for i := 1; i < n; i++ {
+		start = period.start(start.AddDate(0, 0, -1))
+	}
+
+	for i := 0; i < n; i++ {
+		end := period.next(start)
+		p := PeriodStats{Start: start}
+
+		for _, item := range all {
+			created := !item.CreatedAt.Before(start) && item.CreatedAt.Before(end)
+			completed := item.Done && !item.CompletedAt.Before(start) && item.CompletedAt.Before(end)
+			if created {
+				p.Created++
+			}
+			if completed {
+				p.Completed++
+			}
+			if item.CreatedAt.Before(end) && (!item.Done || !item.CompletedAt.Before(end)) {
+				p.Open++
+			}
+		}
+
+		stats.Periods = append(stats.Periods, p)
+		start = end
+	}
+
+	return stats
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	slices.Sort(durations)
+	mid := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[mid-1] + durations[mid]) / 2
+	}
+	return durations[mid]
+}
+
+// streaks returns the current and longest runs of consecutive days found
+// in days, which holds the start of each day with a completion.
+func streaks(days map[time.Time]bool, now time.Time) (current, longest int) {
+	sorted := make([]time.Time, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	slices.SortFunc(sorted, time.Time.Compare)
+
+	run := 0
+	for i, day := range sorted {
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+
+	day := StartOfDay(now)
+	if !days[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return current, longest
+}
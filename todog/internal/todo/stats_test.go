@@ -0,0 +1,73 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestComputeStats(t *testing.T) {
+	// Thursday
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	at := func(month, day int) time.Time {
+		return time.Date(2026, time.Month(month), day, 9, 0, 0, 0, time.UTC)
+	}
+
+	items := []todo.Item{
+		{Task: "open", CreatedAt: at(10, 1)},
+		{Task: "done this week", Done: true, CreatedAt: at(10, 12), CompletedAt: at(10, 14)},
+		{Task: "done today", Done: true, CreatedAt: at(10, 13), CompletedAt: at(10, 15)},
+	}
+	archived := []todo.Item{
+		{Task: "done last week", Done: true, CreatedAt: at(10, 1), CompletedAt: at(10, 7)},
+		{Task: "done before", Done: true, CreatedAt: at(10, 1), CompletedAt: at(10, 6)},
+	}
+
+	stats := todo.ComputeStats(items, archived, now, todo.Week, 3)
+
+	assert.Equal(t, 1, stats.Open)
+	assert.Equal(t, 2, stats.Completed)
+	assert.Equal(t, 2, stats.Archived)
+	assert.Equal(t, 2, stats.CurrentStreak)
+	assert.Equal(t, 2, stats.LongestStreak)
+	// 2d, 2d, 5d, 6d
+	assert.Equal(t, 84*time.Hour, stats.MedianTimeToComplete)
+
+	require.Len(t, stats.Periods, 3)
+
+	monday := func(month, day int) time.Time {
+		return time.Date(2026, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	}
+	assert.Equal(t, []todo.PeriodStats{
+		{Start: monday(9, 28), Created: 3, Completed: 0, Open: 3},
+		{Start: monday(10, 5), Created: 0, Completed: 2, Open: 1},
+		{Start: monday(10, 12), Created: 2, Completed: 2, Open: 1},
+	}, stats.Periods, "weeks start on Monday")
+}
+
+func TestStreakEndingYesterday(t *testing.T) {
+	now := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	items := []todo.Item{
+		{Done: true, CompletedAt: time.Date(2026, 10, 14, 20, 0, 0, 0, time.UTC)},
+		{Done: true, CompletedAt: time.Date(2026, 10, 10, 20, 0, 0, 0, time.UTC)},
+		{Done: true, CompletedAt: time.Date(2026, 10, 9, 20, 0, 0, 0, time.UTC)},
+		{Done: true, CompletedAt: time.Date(2026, 10, 8, 20, 0, 0, 0, time.UTC)},
+	}
+
+	stats := todo.ComputeStats(items, nil, now, todo.Day, 1)
+	assert.Equal(t, 1, stats.CurrentStreak)
+	assert.Equal(t, 3, stats.LongestStreak)
+}
+
+func TestParsePeriod(t *testing.T) {
+	p, err := todo.ParsePeriod("day")
+	require.NoError(t, err)
+	assert.Equal(t, todo.Day, p)
+
+	_, err = todo.ParsePeriod("month")
+	assert.Error(t, err)
+}
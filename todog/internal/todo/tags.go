@@ -0,0 +1,83 @@
+package todo
+
+import (
+	"slices"
+	"strings"
+)
+
+// TagCount summarizes how many open and done items carry a tag.
+type TagCount struct {
+	Tag  string
+	Open int
+	Done int
+}
+
+// ParseTags extracts todo.txt style +project and @context tags from task
+// text. Tags keep their prefix and are returned in order of first appearance.
+func ParseTags(task string) (projects, contexts []string) {
+	for _, word := range strings.Fields(task) {
+		if len(word) < 2 {
+			continue
+		}
+
+		switch word[0] {
+		case '+':
+			if !slices.Contains(projects, word) {
+				projects = append(projects, word)
+			}
+		case '@':
+			if !slices.Contains(contexts, word) {
+				contexts = append(contexts, word)
+			}
+		}
+	}
+
+	return projects, contexts
+}
+
+// HasTag reports whether the item carries the given +project or @context
+// tag. A tag without a prefix matches either kind.
+func (i Item) HasTag(tag string) bool {
+	switch {
+	case strings.HasPrefix(tag, "+"):
+		return slices.Contains(i.Projects, tag)
+	case strings.HasPrefix(tag, "@"):
+		return slices.Contains(i.Contexts, tag)
+	}
+
+	return slices.Contains(i.Projects, "+"+tag) || slices.Contains(i.Contexts, "@"+tag)
+}
+
+// TagCounts tallies open and done items for every tag in the list, with
+// projects listed before contexts and each group sorted by name.
+func (l *List) TagCounts() []TagCount {
+	counts := make(map[string]*TagCount)
+
+	for _, item := range *l {
+		for _, tag := range slices.Concat(item.Projects, item.Contexts) {
+			tc, ok := counts[tag]
+			if !ok {
+				tc = &TagCount{Tag: tag}
+				counts[tag] = tc
+			}
+
+			if item.Done {
+				tc.Done++
+			} else {
+				tc.Open++
+			}
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for _, tc := range counts {
+		result = append(result, *tc)
+	}
+
+	// '+' sorts before '@', so projects come first.
+	slices.SortFunc(result, func(a, b TagCount) int {
+		return strings.Compare(a.Tag, b.Tag)
+	})
+
+	return result
+}
@@ -0,0 +1,172 @@
+package todo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Interval is a span of time tracked against a task. A zero End means the
+// timer is still running.
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Running reports whether the interval hasn't been stopped yet.
+func (iv Interval) Running() bool {
+	return iv.End.IsZero()
+}
+
+// Duration returns the interval's length, counting a running interval up
+// to now.
+func (iv Interval) Duration(now time.Time) time.Duration {
+	if iv.Running() {
+		return now.Sub(iv.Start)
+	}
+	return iv.End.Sub(iv.Start)
+}
+
+// overlap returns how much of the interval falls within [from, to).
+func (iv Interval) overlap(from, to, now time.Time) time.Duration {
+	start, end := iv.Start, iv.End
+	if iv.Running() {
+		end = now
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	return max(0, end.Sub(start))
+}
+
+// Tracking reports whether the item has a running timer.
+func (i Item) Tracking() bool {
+	n := len(i.TimeLog)
+	return n > 0 && i.TimeLog[n-1].Running()
+}
+
+// TrackedTime returns the total time tracked against the item, including a
+// running timer up to now.
+func (i Item) TrackedTime(now time.Time) time.Duration {
+	var total time.Duration
+	for _, iv := range i.TimeLog {
+		total += iv.Duration(now)
+	}
+	return total
+}
+
+// TrackedBetween returns the time tracked against the item within
+// [from, to).
+func (i Item) TrackedBetween(from, to, now time.Time) time.Duration {
+	var total time.Duration
+	for _, iv := range i.TimeLog {
+		total += iv.overlap(from, to, now)
+	}
+	return total
+}
+
+func (i *Item) stopTimer(now time.Time) {
+	if i.Tracking() {
+		i.TimeLog[len(i.TimeLog)-1].End = now
+	}
+}
+
+// Tracking returns the position of the task whose timer is running, or 0
+// if none is.
+func (l *List) Tracking() int {
+	for n, item := range *l {
+		if item.Tracking() {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+// StartTimer starts tracking time against the i-th task. Only one timer
+// runs at a time, so any other running timer is stopped first; its
+// position is returned, or 0 if there was none.
+func (l *List) StartTimer(i int, now time.Time) (stopped int, err error) {
+	if i <= 0 || i > len(*l) {
+		return 0, notFound(i)
+	}
+
+	item := &(*l)[i-1]
+	if item.Done {
+		return 0, fmt.Errorf("item %d is already completed", i)
+	}
+	if item.Tracking() {
+		return 0, fmt.Errorf("item %d is already being tracked", i)
+	}
+
+	// Stop every running timer, in case a hand-edited file has several.
+	for n := range *l {
+		if (*l)[n].Tracking() {
+			(*l)[n].stopTimer(now)
+			stopped = n + 1
+		}
+	}
+
+	item.TimeLog = append(item.TimeLog, Interval{Start: now})
+	return stopped, nil
+}
+
+// StopTimer stops the running timer, returning the position of its task and
+// the interval it recorded.
+func (l *List) StopTimer(now time.Time) (int, Interval, error) {
+	i := l.Tracking()
+	if i == 0 {
+		return 0, Interval{}, errors.New("no timer is running")
+	}
+
+	item := &(*l)[i-1]
+	item.stopTimer(now)
+	return i, item.TimeLog[len(item.TimeLog)-1], nil
+}
+
+// TimeTotal is the time tracked against one task or tag.
+type TimeTotal struct {
+	// ID is the task's ID; it is empty for tags.
+	ID   string
+	Name string
+	Time time.Duration
+}
+
+// Timesheet totals the time tracked within [from, to) per task and per
+// +project or @context tag, largest first. Tasks with no time are left out.
+func Timesheet(items []Item, from, to, now time.Time) (tasks, tags []TimeTotal) {
+	byTag := make(map[string]time.Duration)
+
+	for _, item := range items {
+		d := item.TrackedBetween(from, to, now)
+		if d <= 0 {
+			continue
+		}
+
+		tasks = append(tasks, TimeTotal{ID: item.ID, Name: item.Task, Time: d})
+		for _, tag := range append(append([]string(nil), item.Projects...), item.Contexts...) {
+			byTag[tag] += d
+		}
+	}
+
+	for tag, d := range byTag {
+		tags = append(tags, TimeTotal{Name: tag, Time: d})
+	}
+
+	sortTotals(tasks)
+	sortTotals(tags)
+	return tasks, tags
+}
+
+func sortTotals(totals []TimeTotal) {
+	slices.SortStableFunc(totals, func(a, b TimeTotal) int {
+		if c := cmp.Compare(b.Time, a.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+}
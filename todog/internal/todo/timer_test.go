@@ -0,0 +1,93 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func TestListTimer(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+
+	var l todo.List
+	l.Add("Write report +work")
+	l.Add("Review PR +work @office")
+
+	stopped, err := l.StartTimer(1, start)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stopped)
+	assert.Equal(t, 1, l.Tracking())
+
+	_, err = l.StartTimer(1, start)
+	assert.ErrorContains(t, err, "already being tracked")
+
+	// Starting another task stops the running timer.
+	stopped, err = l.StartTimer(2, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, stopped)
+	assert.Equal(t, 2, l.Tracking())
+	assert.Equal(t, time.Hour, l[0].TrackedTime(start.Add(5*time.Hour)))
+
+	num, interval, err := l.StopTimer(start.Add(90 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, num)
+	assert.Equal(t, 30*time.Minute, interval.Duration(start))
+	assert.Equal(t, 0, l.Tracking())
+
+	_, _, err = l.StopTimer(start)
+	assert.ErrorContains(t, err, "no timer is running")
+
+	// Completing a task stops its timer, and done tasks can't be started.
+	_, err = l.StartTimer(1, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, l.Complete(1))
+	assert.False(t, l[0].Tracking())
+
+	_, err = l.StartTimer(1, start)
+	assert.ErrorContains(t, err, "already completed")
+
+	_, err = l.StartTimer(3, start)
+	assert.Error(t, err)
+}
+
+func TestTimesheet(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	now := monday.AddDate(0, 0, 2).Add(10 * time.Hour)
+
+	items := []todo.Item{
+		{ID: "aaaa", Task: "Write report +work", Projects: []string{"+work"}, TimeLog: []todo.Interval{
+			// Only the hour after midnight on Monday counts.
+			{Start: monday.Add(-time.Hour), End: monday.Add(time.Hour)},
+			{Start: monday.Add(25 * time.Hour), End: monday.Add(26 * time.Hour)},
+		}},
+		{ID: "bbbb", Task: "Review PR +work @office", Projects: []string{"+work"}, Contexts: []string{"@office"}, TimeLog: []todo.Interval{
+			// Still running.
+			{Start: now.Add(-3 * time.Hour)},
+		}},
+		{ID: "cccc", Task: "Untracked"},
+	}
+
+	tasks, tags := todo.Timesheet(items, monday, now, now)
+	assert.Equal(t, []todo.TimeTotal{
+		{ID: "bbbb", Name: "Review PR +work @office", Time: 3 * time.Hour},
+		{ID: "aaaa", Name: "Write report +work", Time: 2 * time.Hour},
+	}, tasks)
+	assert.Equal(t, []todo.TimeTotal{
+		{Name: "+work", Time: 5 * time.Hour},
+		{Name: "@office", Time: 3 * time.Hour},
+	}, tags)
+
+	tasks, _ = todo.Timesheet(items, time.Time{}, now, now)
+	assert.Equal(t, 3*time.Hour, tasks[1].Time)
+	assert.Equal(t, 3*time.Hour, tasks[0].Time)
+}
+
+func TestStartOfWeek(t *testing.T) {
+	sunday := time.Date(2026, 10, 18, 15, 4, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), todo.StartOfWeek(sunday))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), todo.StartOfWeek(sunday.AddDate(0, 0, -6)))
+}
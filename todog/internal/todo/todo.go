@@ -5,96 +5,400 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Item represents a single to-do task.
 type Item struct {
-	Task        string    `json:"task"`
-	Done        bool      `json:"done"`
-	CreatedAt   time.Time `json:"created_at"`
-	CompletedAt time.Time `json:"completed_at"`
+	ID          string      `json:"id"`
+	Task        string      `json:"task"`
+	Done        bool        `json:"done"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt time.Time   `json:"completed_at"`
+	Priority    string      `json:"priority,omitempty"`
+	Due         time.Time   `json:"due,omitzero"`
+	Projects    []string    `json:"projects,omitempty"`
+	Contexts    []string    `json:"contexts,omitempty"`
+	ReopenedAt  []time.Time `json:"reopened_at,omitempty"`
+	Recur       string      `json:"recur,omitempty"`
+	Spawned     string      `json:"spawned,omitempty"` // next occurrence, once created
+	Parent      string      `json:"parent,omitempty"`
+	TimeLog     []Interval  `json:"time_log,omitempty"`
+	Notes       []Note      `json:"notes,omitempty"`
+	Attachments []string    `json:"attachments,omitempty"`
 }
 
 // List is a collection of to-do items.
 type List []Item
 
+// NotFoundError reports a task number or ID that matches no item.
+type NotFoundError struct {
+	Ref string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("item %s does not exist", e.Ref)
+}
+
+// RangeError reports a position range such as 8-5 that can't be expanded,
+// or a position outside the list when Max is set.
+type RangeError struct {
+	Range string
+	Max   int // number of tasks, for a single position out of range
+}
+
+func (e *RangeError) Error() string {
+	if e.Max > 0 {
+		return fmt.Sprintf("position %s is out of range (1-%d)", e.Range, e.Max)
+	}
+	return fmt.Sprintf("invalid range %q", e.Range)
+}
+
+func notFound(i int) error {
+	return &NotFoundError{Ref: strconv.Itoa(i)}
+}
+
 func (l *List) String() string {
 	var b strings.Builder // Efficiently builds the final output string
 
-	for i, t := range *l {
+	order, depths := TreeOrder(*l)
+	for n, i := range order {
+		t := (*l)[i]
 		status := "[ ]"
 
 		if t.Done {
 			status = "[x]"
 		}
 
-		fmt.Fprintf(&b, "%d. %s %s\n", i+1, status, t.Task)
+		b.WriteString(strings.Repeat("  ", depths[n]))
+
+		if t.Priority != "" {
+			fmt.Fprintf(&b, "%d. %s (%s) %s", i+1, status, t.Priority, t.Task)
+		} else {
+			fmt.Fprintf(&b, "%d. %s %s", i+1, status, t.Task)
+		}
+
+		if done, total := l.Progress(i + 1); total > 0 {
+			fmt.Fprintf(&b, " (%d/%d done)", done, total)
+		}
+
+		b.WriteString("\n")
 	}
 
 	return b.String()
 }
 
-// Add creates a new task and appends it to the list.
+// Add creates a new task with a fresh ID and appends it to the list. Any
+// +project and @context tags in the task text are recorded on the item.
 func (l *List) Add(task string) Item {
+	projects, contexts := ParseTags(task)
+
 	item := Item{
+		ID:          l.newID(),
 		Task:        task,
 		Done:        false,
 		CreatedAt:   time.Now(),
 		CompletedAt: time.Time{},
+		Projects:    projects,
+		Contexts:    contexts,
 	}
 
 	*l = append(*l, item)
 	return item
 }
 
-// Complete marks the i-th task as done.
+// Complete marks the i-th task as done. Completing an open recurring task
+// appends its next occurrence to the end of the list, once: a task that is
+// reopened and completed again has already been rescheduled.
 func (l *List) Complete(i int) error {
 	if i <= 0 || i > len(*l) {
-		return fmt.Errorf("item %d does not exist", i)
+		return notFound(i)
 	}
 
+	now := time.Now()
+	item := (*l)[i-1]
+
 	(*l)[i-1].Done = true
-	(*l)[i-1].CompletedAt = time.Now()
+	(*l)[i-1].CompletedAt = now
+	(*l)[i-1].stopTimer(now)
+
+	if item.Recur != "" && !item.Done && item.Spawned == "" {
+		next := l.Add(item.Task)
+		next.Priority = item.Priority
+		next.Recur = item.Recur
+		next.Parent = item.Parent
+		next.Attachments = slices.Clone(item.Attachments)
+		next.Due = NextDue(item, now)
+		(*l)[len(*l)-1] = next
+		(*l)[i-1].Spawned = next.ID
+	}
+
+	return nil
+}
+
+// SetRecurrence sets the recurrence rule of the i-th task. An empty rule
+// makes it a one-off task.
+func (l *List) SetRecurrence(i int, rule string) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	r, err := ParseRecurrence(rule)
+	if err != nil {
+		return err
+	}
+
+	(*l)[i-1].Recur = r
 	return nil
 }
 
-// Delete removes the i-th task from the list.
+// Reopen marks the i-th task as not done again, clearing its completion
+// time and remembering when it was reopened.
+func (l *List) Reopen(i int) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	item := &(*l)[i-1]
+	if !item.Done {
+		return fmt.Errorf("item %d is not completed", i)
+	}
+
+	item.Done = false
+	item.CompletedAt = time.Time{}
+	item.ReopenedAt = append(item.ReopenedAt, time.Now())
+	return nil
+}
+
+// Toggle completes the i-th task if it is open, or reopens it if it is done.
+func (l *List) Toggle(i int) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	if (*l)[i-1].Done {
+		return l.Reopen(i)
+	}
+	return l.Complete(i)
+}
+
+// Edit replaces the text of the i-th task, keeping its other fields, and
+// re-reads its +project and @context tags.
+func (l *List) Edit(i int, task string) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	task = strings.TrimSpace(task)
+	if task == "" {
+		return errors.New("task cannot be blank")
+	}
+
+	item := &(*l)[i-1]
+	item.Task = task
+	item.Projects, item.Contexts = ParseTags(task)
+	return nil
+}
+
+// Prioritize sets the priority of the i-th task. An empty priority clears it.
+func (l *List) Prioritize(i int, priority string) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	p, err := ParsePriority(priority)
+	if err != nil {
+		return err
+	}
+
+	(*l)[i-1].Priority = p
+	return nil
+}
+
+// Delete removes the i-th task from the list. Its subtasks move up to take
+// its place under its own parent.
 func (l *List) Delete(i int) error {
 	if i <= 0 || i > len(*l) {
-		return fmt.Errorf("item %d does not exist", i)
+		return notFound(i)
+	}
+
+	deleted := (*l)[i-1]
+	for n := range *l {
+		if (*l)[n].Parent == deleted.ID {
+			(*l)[n].Parent = deleted.Parent
+		}
 	}
 
 	*l = slices.Delete(*l, i-1, i)
 	return nil
 }
 
-// Save writes the list to a file in JSON format.
+// Clone returns a copy of the list that shares no memory with it, so
+// changing either one leaves the other as it was.
+func (l List) Clone() List {
+	clone := slices.Clone(l)
+	for i := range clone {
+		item := &clone[i]
+		item.Projects = slices.Clone(item.Projects)
+		item.Contexts = slices.Clone(item.Contexts)
+		item.ReopenedAt = slices.Clone(item.ReopenedAt)
+		item.TimeLog = slices.Clone(item.TimeLog)
+		item.Notes = slices.Clone(item.Notes)
+		item.Attachments = slices.Clone(item.Attachments)
+	}
+	return clone
+}
+
+// Save writes the list to a file in JSON format. The data is written to a
+// temporary file in the same directory and renamed into place, so readers
+// never see a partially written list and a crash leaves the old file intact.
 func (l *List) Save(filename string) error {
 	data, err := json.MarshalIndent(l, "", "  ") // prettier formatting
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, data, 0644)
+	return writeFileAtomic(filename, data)
+}
+
+// writeFileAtomic replaces filename with data via a temporary file and rename.
+func writeFileAtomic(filename string, data []byte) error {
+	// Replace the target of a symlinked file rather than the link itself.
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = resolved
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
 }
 
-// Get reads the list from a JSON file, if it exists.
+// Get reads the list from a JSON file, if it exists. Items saved before
+// IDs existed are given one derived from their content; it becomes
+// permanent the next time the list is saved.
 func (l *List) Get(filename string) error {
+	_, err := l.get(filename)
+	return err
+}
+
+// Load is Get for callers that don't hold the file lock. If any item had to
+// be given an ID, it takes the lock and saves the list straight away, so
+// every later read sees the same IDs.
+func (l *List) Load(filename string) error {
+	migrated, err := l.get(filename)
+	if err != nil || !migrated {
+		return err
+	}
+
+	unlock, err := Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Read again: another process may have changed the file meanwhile.
+	*l = nil
+	if migrated, err = l.get(filename); err != nil || !migrated {
+		return err
+	}
+	return l.Save(filename)
+}
+
+// get reads the list and reports whether any item was given an ID.
+func (l *List) get(filename string) (migrated bool, err error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			// No file yet; treat as empty list
-			return nil
+			return false, nil
 		}
-		return err
+		return false, err
 	}
 
 	if len(data) == 0 {
-		return nil
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, l); err != nil {
+		return false, err
+	}
+
+	// Collect every stored ID first so a derived one can't take an ID that
+	// a later item already has.
+	taken := make(map[string]bool, len(*l))
+	for _, item := range *l {
+		if item.ID != "" {
+			taken[item.ID] = true
+		}
+	}
+
+	for i := range *l {
+		item := &(*l)[i]
+
+		if item.ID == "" {
+			item.ID = derivedID(*item, taken)
+			taken[item.ID] = true
+			migrated = true
+		}
+
+		// Files written before tags were stored only have them in the task text.
+		if item.Projects == nil && item.Contexts == nil {
+			item.Projects, item.Contexts = ParseTags(item.Task)
+		}
+	}
+
+	return migrated, nil
+}
+
+// ParsePriority normalizes a priority level to a single uppercase letter
+// from A (highest) to Z (lowest). An empty string or "none" means no priority.
+func ParsePriority(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" || s == "NONE" {
+		return "", nil
+	}
+
+	if len(s) != 1 || s[0] < 'A' || s[0] > 'Z' {
+		return "", fmt.Errorf("invalid priority %q (expected A-Z)", s)
+	}
+
+	return s, nil
+}
+
+// ComparePriority orders priorities from highest to lowest, placing
+// unprioritized items last. It returns a negative number when a ranks above b.
+func ComparePriority(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
 	}
 
-	return json.Unmarshal(data, l)
+	return strings.Compare(a, b)
 }
@@ -2,6 +2,7 @@ package todo_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -70,3 +71,222 @@ func TestSaveGet(t *testing.T) {
 	assert.Equal(t, list1[0].Done, list2[0].Done)
 	assert.WithinDuration(t, list1[0].CreatedAt, list2[0].CreatedAt, 2*time.Second)
 }
+
+func TestPrioritize(t *testing.T) {
+	var list todo.List
+	list.Add("Task 1")
+
+	require.NoError(t, list.Prioritize(1, "b"))
+	assert.Equal(t, "B", list[0].Priority)
+
+	require.NoError(t, list.Prioritize(1, "none"))
+	assert.Empty(t, list[0].Priority)
+
+	assert.Error(t, list.Prioritize(1, "high"))
+	assert.Error(t, list.Prioritize(2, "A"))
+}
+
+func TestComparePriority(t *testing.T) {
+	assert.Negative(t, todo.ComparePriority("A", "B"))
+	assert.Positive(t, todo.ComparePriority("C", "B"))
+	assert.Negative(t, todo.ComparePriority("Z", ""))
+	assert.Positive(t, todo.ComparePriority("", "A"))
+	assert.Zero(t, todo.ComparePriority("", ""))
+}
+
+func TestGetLegacyFile(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "todo-test-")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	legacy := `[{"task":"Old task @home","done":false,"created_at":"2025-01-01T00:00:00Z","completed_at":"0001-01-01T00:00:00Z"}]`
+	require.NoError(t, os.WriteFile(tmpFile.Name(), []byte(legacy), 0644))
+
+	var list todo.List
+	require.NoError(t, list.Get(tmpFile.Name()))
+
+	require.Len(t, list, 1)
+	assert.Equal(t, "Old task @home", list[0].Task)
+	assert.Empty(t, list[0].Priority)
+	assert.Equal(t, []string{"@home"}, list[0].Contexts)
+	assert.NotEmpty(t, list[0].ID)
+
+	// The migrated ID must be stable across reads of an unchanged file.
+	var again todo.List
+	require.NoError(t, again.Get(tmpFile.Name()))
+	assert.Equal(t, list[0].ID, again[0].ID)
+}
+
+func TestListClone(t *testing.T) {
+	var list todo.List
+	list.Add("Write report +work @office")
+	require.NoError(t, list.Attach(1, "report.md"))
+
+	clone := list.Clone()
+	clone[0].Task = "Changed"
+	clone[0].Projects[0] = "+home"
+	clone[0].Attachments[0] = "other.md"
+	clone.Add("Another")
+
+	require.Len(t, list, 1)
+	assert.Equal(t, "Write report +work @office", list[0].Task)
+	assert.Equal(t, []string{"+work"}, list[0].Projects)
+	assert.Equal(t, []string{"report.md"}, list[0].Attachments)
+}
+
+func TestLoadMigratesLegacyIDs(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	legacy := `{"task":"Old task","done":false,"created_at":"2025-01-01T00:00:00Z"}`
+	require.NoError(t, os.WriteFile(filename, []byte("["+legacy+"]"), 0644))
+
+	var alone todo.List
+	require.NoError(t, alone.Get(filename))
+	derived := alone[0].ID
+
+	// A later item already has the ID the legacy item would derive.
+	mixed := "[" + legacy + `,{"id":"` + derived + `","task":"New task","created_at":"2026-01-01T00:00:00Z"}]`
+	require.NoError(t, os.WriteFile(filename, []byte(mixed), 0644))
+
+	var list todo.List
+	require.NoError(t, list.Load(filename))
+	require.Len(t, list, 2)
+	assert.Equal(t, derived, list[1].ID)
+	assert.NotEqual(t, derived, list[0].ID)
+
+	// Load saved the derived ID, so it can't change later.
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"id": "`+list[0].ID+`"`)
+
+	var again todo.List
+	require.NoError(t, again.Get(filename))
+	assert.Equal(t, list[0].ID, again[0].ID)
+}
+
+func TestAddParsesTags(t *testing.T) {
+	var list todo.List
+
+	item := list.Add("Call Bob +release @phone about +release notes")
+
+	assert.Equal(t, []string{"+release"}, item.Projects)
+	assert.Equal(t, []string{"@phone"}, item.Contexts)
+	assert.True(t, item.HasTag("@phone"))
+	assert.True(t, item.HasTag("release"))
+	assert.False(t, item.HasTag("@office"))
+}
+
+func TestTagCounts(t *testing.T) {
+	var list todo.List
+	list.Add("Write docs +release @office")
+	list.Add("Tag build +release")
+	list.Add("Water plants @home")
+	require.NoError(t, list.Complete(2))
+
+	counts := list.TagCounts()
+
+	assert.Equal(t, []todo.TagCount{
+		{Tag: "+release", Open: 1, Done: 1},
+		{Tag: "@home", Open: 1, Done: 0},
+		{Tag: "@office", Open: 1, Done: 0},
+	}, counts)
+}
+
+func TestResolve(t *testing.T) {
+	var list todo.List
+	list.Add("Task 1")
+	item := list.Add("Task 2")
+
+	assert.NotEqual(t, list[0].ID, list[1].ID)
+
+	num, err := list.Resolve("2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, num)
+
+	num, err = list.Resolve(item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, num)
+
+	require.NoError(t, list.Delete(1))
+	num, err = list.Resolve(item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, num, "ID should follow the item after positions shift")
+
+	_, err = list.Resolve("3")
+	assert.Error(t, err)
+	_, err = list.Resolve("zzzz")
+	assert.Error(t, err)
+}
+
+func TestEdit(t *testing.T) {
+	var list todo.List
+	original := list.Add("Fix tpyo +docs")
+
+	require.NoError(t, list.Edit(1, "Fix typo +release @office"))
+
+	assert.Equal(t, "Fix typo +release @office", list[0].Task)
+	assert.Equal(t, original.ID, list[0].ID)
+	assert.Equal(t, original.CreatedAt, list[0].CreatedAt)
+	assert.Equal(t, []string{"+release"}, list[0].Projects)
+	assert.Equal(t, []string{"@office"}, list[0].Contexts)
+
+	assert.EqualError(t, list.Edit(1, "   "), "task cannot be blank")
+	assert.Error(t, list.Edit(2, "Missing"))
+}
+
+func TestReopen(t *testing.T) {
+	var list todo.List
+	list.Add("Task 1")
+
+	assert.Error(t, list.Reopen(1), "open tasks cannot be reopened")
+
+	require.NoError(t, list.Complete(1))
+	require.NoError(t, list.Reopen(1))
+
+	assert.False(t, list[0].Done)
+	assert.True(t, list[0].CompletedAt.IsZero())
+	assert.Len(t, list[0].ReopenedAt, 1)
+}
+
+func TestToggle(t *testing.T) {
+	var list todo.List
+	list.Add("Task 1")
+
+	require.NoError(t, list.Toggle(1))
+	assert.True(t, list[0].Done)
+
+	require.NoError(t, list.Toggle(1))
+	assert.False(t, list[0].Done)
+	assert.Len(t, list[0].ReopenedAt, 1)
+
+	assert.Error(t, list.Toggle(2))
+}
+
+func TestResolveAll(t *testing.T) {
+	var list todo.List
+	for _, task := range []string{"one", "two", "three", "four", "five"} {
+		list.Add(task)
+	}
+
+	nums, err := list.ResolveAll([]string{"5", "1-3", list[1].ID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 1, 2, 3}, nums)
+
+	var notFound *todo.NotFoundError
+	_, err = list.ResolveAll([]string{"1", "4-9"})
+	require.ErrorAs(t, err, &notFound)
+	assert.EqualError(t, err, "item 6 does not exist")
+
+	_, err = list.ResolveAll([]string{"0-2"})
+	assert.ErrorAs(t, err, &notFound)
+
+	var rangeErr *todo.RangeError
+	_, err = list.ResolveAll([]string{"3-1"})
+	assert.ErrorAs(t, err, &rangeErr)
+
+	_, err = list.ResolveAll([]string{"a-b"})
+	assert.ErrorAs(t, err, &rangeErr)
+
+	_, err = list.ResolveAll([]string{"2", "nope"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,252 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTodoTxt reads items in todo.txt format, one per non-blank line:
+//
+//	x 2026-10-01 2026-09-20 Ship release +v2 @office due:2026-10-01 id:kxbt
+//	(A) 2026-09-21 Call Bob @phone rec:1w
+//
+// Completion, priority (also pri:A on completed lines), creation date, and
+// the due:, rec:, id:, and parent: keys map onto Item fields. Everything
+// else, including +project and @context tags, stays in the task text.
+func ParseTodoTxt(r io.Reader) ([]Item, error) {
+	var items []Item
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		item, err := parseTodoTxtLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		items = append(items, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func parseTodoTxtLine(line string) (Item, error) {
+	var item Item
+	words := strings.Fields(line)
+
+	if words[0] == "x" {
+		item.Done = true
+		words = words[1:]
+
+		if len(words) > 0 {
+			if d, ok := parseTodoTxtDate(words[0]); ok {
+				item.CompletedAt = d
+				words = words[1:]
+			}
+		}
+	} else if len(words[0]) == 3 && words[0][0] == '(' && words[0][2] == ')' {
+		p, err := ParsePriority(words[0][1:2])
+		if err != nil {
+			return Item{}, err
+		}
+		item.Priority = p
+		words = words[1:]
+	}
+
+	if len(words) > 0 {
+		if d, ok := parseTodoTxtDate(words[0]); ok {
+			item.CreatedAt = d
+			words = words[1:]
+		}
+	}
+
+	var text []string
+	for _, word := range words {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			text = append(text, word)
+			continue
+		}
+
+		switch key {
+		case "due":
+			d, ok := parseTodoTxtDate(value)
+			if !ok {
+				return Item{}, fmt.Errorf("invalid due date %q", value)
+			}
+			item.Due = d
+		case "rec":
+			rule, err := parseTodoTxtRecurrence(value)
+			if err != nil {
+				return Item{}, err
+			}
+			item.Recur = rule
+		case "pri":
+			p, err := ParsePriority(value)
+			if err != nil {
+				return Item{}, err
+			}
+			item.Priority = p
+		case "id":
+			item.ID = value
+		case "parent":
+			item.Parent = value
+		default:
+			text = append(text, word)
+		}
+	}
+
+	item.Task = strings.Join(text, " ")
+	if item.Task == "" {
+		return Item{}, fmt.Errorf("task cannot be blank")
+	}
+	item.Projects, item.Contexts = ParseTags(item.Task)
+
+	return item, nil
+}
+
+func parseTodoTxtDate(s string) (time.Time, bool) {
+	d, err := time.ParseInLocation(DateLayout, s, time.Local)
+	return d, err == nil
+}
+
+// parseTodoTxtRecurrence converts the rec: extension (e.g. 1w, +2d, 3m)
+// into a recurrence rule. The strict "+" form is treated like the plain one.
+func parseTodoTxtRecurrence(s string) (string, error) {
+	spec := strings.TrimPrefix(s, "+")
+	if len(spec) < 2 {
+		return "", fmt.Errorf("invalid recurrence %q", s)
+	}
+
+	units := map[byte]string{'d': "days", 'w': "weeks", 'm': "months", 'y': "years"}
+	unit, ok := units[spec[len(spec)-1]]
+	n, err := strconv.Atoi(spec[:len(spec)-1])
+	if !ok || err != nil || n <= 0 {
+		return "", fmt.Errorf("invalid recurrence %q", s)
+	}
+
+	return ParseRecurrence(fmt.Sprintf("every %d %s", n, unit))
+}
+
+// todoTxtRecurrence converts a recurrence rule into the rec: extension.
+func todoTxtRecurrence(rule string) string {
+	n := "1"
+	unit := rule
+
+	if rest, ok := strings.CutPrefix(rule, "every "); ok {
+		n, unit, _ = strings.Cut(rest, " ")
+		unit = recurrenceUnits[strings.TrimSuffix(unit, "s")]
+	}
+
+	suffix := map[string]string{"daily": "d", "weekly": "w", "monthly": "m", "yearly": "y"}
+	return n + suffix[unit]
+}
+
+// FormatTodoTxt renders an item as a single todo.txt line.
+func FormatTodoTxt(item Item) string {
+	var parts []string
+
+	if item.Done {
+		parts = append(parts, "x")
+		if !item.CompletedAt.IsZero() {
+			parts = append(parts, item.CompletedAt.Format(DateLayout))
+		}
+	} else if item.Priority != "" {
+		parts = append(parts, "("+item.Priority+")")
+	}
+
+	if !item.CreatedAt.IsZero() {
+		parts = append(parts, item.CreatedAt.Format(DateLayout))
+	}
+
+	parts = append(parts, item.Task)
+
+	// todo.txt drops the leading priority once a task is done.
+	if item.Done && item.Priority != "" {
+		parts = append(parts, "pri:"+item.Priority)
+	}
+	if item.HasDue() {
+		parts = append(parts, "due:"+item.Due.Format(DateLayout))
+	}
+	if item.Recur != "" {
+		parts = append(parts, "rec:"+todoTxtRecurrence(item.Recur))
+	}
+	if item.ID != "" {
+		parts = append(parts, "id:"+item.ID)
+	}
+	if item.Parent != "" {
+		parts = append(parts, "parent:"+item.Parent)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// WriteTodoTxt writes items in todo.txt format, one per line.
+func WriteTodoTxt(w io.Writer, items []Item) error {
+	for _, item := range items {
+		if _, err := fmt.Fprintln(w, FormatTodoTxt(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import appends items read from another source. Missing creation and
+// completion times are stamped with the current time. IDs that are missing,
+// not in the form todog generates, or already taken are replaced, and
+// parent links within the batch follow them. Parent links to tasks outside
+// the batch are dropped.
+func (l *List) Import(items []Item) []Item {
+	renamed := make(map[string]string)
+	start := len(*l)
+	now := time.Now()
+
+	for _, item := range items {
+		if item.CreatedAt.IsZero() {
+			item.CreatedAt = now
+		}
+		if item.Done && item.CompletedAt.IsZero() {
+			item.CompletedAt = now
+		}
+
+		if !validID(item.ID) || l.indexOfID(item.ID) >= 0 {
+			id := l.newID()
+			if item.ID != "" {
+				renamed[item.ID] = id
+			}
+			item.ID = id
+		}
+
+		*l = append(*l, item)
+	}
+
+	imported := (*l)[start:]
+	inBatch := make(map[string]bool, len(imported))
+	for _, item := range imported {
+		inBatch[item.ID] = true
+	}
+
+	for i := range imported {
+		parent := imported[i].Parent
+		if id, ok := renamed[parent]; ok {
+			parent = id
+		}
+		if !inBatch[parent] || parent == imported[i].ID {
+			parent = ""
+		}
+		imported[i].Parent = parent
+	}
+
+	return imported
+}
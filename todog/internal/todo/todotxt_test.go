@@ -0,0 +1,141 @@
+package todo_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+const sampleTodoTxt = `(A) 2026-09-21 Call Bob about the launch +release @phone due:2026-10-01 id:kxbt
+x 2026-10-02 2026-09-20 Ship release notes +release pri:B id:mnpq parent:kxbt
+2026-09-22 Rotate on-call @work rec:1w id:rstv
+x Water plants see http://example.com/plants id:wxyz
+(C) Write the plan rec:2m id:abcd
+`
+
+func TestParseTodoTxt(t *testing.T) {
+	items, err := todo.ParseTodoTxt(strings.NewReader(sampleTodoTxt))
+	require.NoError(t, err)
+	require.Len(t, items, 5)
+
+	call := items[0]
+	assert.Equal(t, "Call Bob about the launch +release @phone", call.Task)
+	assert.Equal(t, "A", call.Priority)
+	assert.False(t, call.Done)
+	assert.Equal(t, "2026-09-21", call.CreatedAt.Format(todo.DateLayout))
+	assert.Equal(t, "2026-10-01", call.Due.Format(todo.DateLayout))
+	assert.Equal(t, []string{"+release"}, call.Projects)
+	assert.Equal(t, []string{"@phone"}, call.Contexts)
+	assert.Equal(t, "kxbt", call.ID)
+
+	notes := items[1]
+	assert.True(t, notes.Done)
+	assert.Equal(t, "B", notes.Priority)
+	assert.Equal(t, "2026-10-02", notes.CompletedAt.Format(todo.DateLayout))
+	assert.Equal(t, "2026-09-20", notes.CreatedAt.Format(todo.DateLayout))
+	assert.Equal(t, "kxbt", notes.Parent)
+
+	assert.Equal(t, "weekly", items[2].Recur)
+	assert.Equal(t, "Water plants see http://example.com/plants", items[3].Task)
+	assert.Equal(t, "every 2 months", items[4].Recur)
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	items, err := todo.ParseTodoTxt(strings.NewReader(sampleTodoTxt))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, todo.WriteTodoTxt(&out, items))
+	assert.Equal(t, sampleTodoTxt, out.String())
+
+	again, err := todo.ParseTodoTxt(&out)
+	require.NoError(t, err)
+	assert.Equal(t, items, again)
+}
+
+func TestTodoTxtExportOfSavedList(t *testing.T) {
+	var list todo.List
+	list.Add("Plan offsite +team @office")
+	require.NoError(t, list.Prioritize(1, "A"))
+	require.NoError(t, list.SetDue(1, time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local)))
+	require.NoError(t, list.SetRecurrence(1, "every 3 days"))
+	list.Add("Book venue")
+	require.NoError(t, list.SetParent(2, 1))
+	require.NoError(t, list.Complete(2))
+
+	var out bytes.Buffer
+	require.NoError(t, todo.WriteTodoTxt(&out, list))
+
+	items, err := todo.ParseTodoTxt(&out)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	for i, item := range items {
+		original := list[i]
+		assert.Equal(t, original.ID, item.ID)
+		assert.Equal(t, original.Task, item.Task)
+		assert.Equal(t, original.Done, item.Done)
+		assert.Equal(t, original.Priority, item.Priority)
+		assert.Equal(t, original.Recur, item.Recur)
+		assert.Equal(t, original.Parent, item.Parent)
+		assert.Equal(t, original.Due.Format(todo.DateLayout), item.Due.Format(todo.DateLayout))
+		assert.Equal(t, original.CreatedAt.Format(todo.DateLayout), item.CreatedAt.Format(todo.DateLayout))
+	}
+}
+
+func TestParseTodoTxtErrors(t *testing.T) {
+	_, err := todo.ParseTodoTxt(strings.NewReader("ok task\n(A) due:someday\n"))
+	assert.EqualError(t, err, `line 2: invalid due date "someday"`)
+
+	_, err = todo.ParseTodoTxt(strings.NewReader("x 2026-10-01\n"))
+	assert.EqualError(t, err, "line 1: task cannot be blank")
+}
+
+func TestImportReassignsClashingIDs(t *testing.T) {
+	var list todo.List
+	existing := list.Add("Existing")
+
+	imported := list.Import([]todo.Item{
+		{ID: existing.ID, Task: "Parent"},
+		{ID: "kid1", Task: "Child", Parent: existing.ID},
+		{Task: "No ID"},
+	})
+
+	require.Len(t, list, 4)
+	require.Len(t, imported, 3)
+
+	assert.NotEqual(t, existing.ID, imported[0].ID)
+	assert.Equal(t, imported[0].ID, imported[1].Parent, "parent link follows the renamed ID")
+	assert.NotEmpty(t, imported[2].ID)
+	assert.False(t, imported[2].CreatedAt.IsZero())
+}
+
+func TestImportReplacesMalformedIDsAndOutsideParents(t *testing.T) {
+	var list todo.List
+	existing := list.Add("Existing")
+
+	imported := list.Import([]todo.Item{
+		{ID: "Task-1", Task: "Parent"},
+		{ID: "abcdefg", Task: "Child", Parent: "Task-1"},
+		{ID: "wxyz", Task: "Grandchild", Parent: "abcdefg"},
+		{ID: "qrst", Task: "Orphan", Parent: existing.ID},
+		{ID: "mnpq", Task: "Lost", Parent: "gone"},
+	})
+	require.Len(t, imported, 5)
+
+	for _, item := range imported[:2] {
+		assert.Regexp(t, `^[a-z]{4}$`, item.ID)
+	}
+	assert.Equal(t, "wxyz", imported[2].ID, "valid IDs are kept")
+
+	assert.Equal(t, imported[0].ID, imported[1].Parent)
+	assert.Equal(t, imported[1].ID, imported[2].Parent)
+	assert.Empty(t, imported[3].Parent, "parents outside the batch are dropped")
+	assert.Empty(t, imported[4].Parent)
+}
@@ -0,0 +1,138 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetParent nests the i-th task under the parent-th task. A parent of 0
+// moves the task back to the top level.
+func (l *List) SetParent(i, parent int) error {
+	if i <= 0 || i > len(*l) {
+		return notFound(i)
+	}
+
+	if parent == 0 {
+		(*l)[i-1].Parent = ""
+		return nil
+	}
+
+	if parent < 0 || parent > len(*l) {
+		return notFound(parent)
+	}
+
+	if parent == i {
+		return errors.New("a task cannot be its own parent")
+	}
+
+	for _, d := range l.Descendants(i) {
+		if d == parent {
+			return fmt.Errorf("item %d is a subtask of item %d", parent, i)
+		}
+	}
+
+	(*l)[i-1].Parent = (*l)[parent-1].ID
+	return nil
+}
+
+// Children returns the positions of the i-th task's direct subtasks.
+func (l *List) Children(i int) []int {
+	if i <= 0 || i > len(*l) {
+		return nil
+	}
+
+	id := (*l)[i-1].ID
+
+	var children []int
+	for n, item := range *l {
+		if item.Parent == id {
+			children = append(children, n+1)
+		}
+	}
+
+	return children
+}
+
+// Descendants returns the positions of every subtask below the i-th task,
+// depth first.
+func (l *List) Descendants(i int) []int {
+	var result []int
+	seen := map[int]bool{i: true}
+
+	var walk func(n int)
+	walk = func(n int) {
+		for _, child := range l.Children(n) {
+			if seen[child] {
+				continue // guard against cycles in hand-edited files
+			}
+			seen[child] = true
+			result = append(result, child)
+			walk(child)
+		}
+	}
+	walk(i)
+
+	return result
+}
+
+// Progress counts how many of the i-th task's subtasks, at any depth, are done.
+func (l *List) Progress(i int) (done, total int) {
+	for _, d := range l.Descendants(i) {
+		total++
+		if (*l)[d-1].Done {
+			done++
+		}
+	}
+
+	return done, total
+}
+
+// TreeOrder arranges items so that each one is followed by its subtasks,
+// returning their indices into items along with each one's nesting depth.
+// Siblings keep their relative order. Items whose parent isn't among items
+// are treated as top-level.
+func TreeOrder(items []Item) (order, depths []int) {
+	present := make(map[string]bool, len(items))
+	for _, item := range items {
+		present[item.ID] = true
+	}
+
+	children := make(map[string][]int)
+	var roots []int
+	for i, item := range items {
+		if item.Parent != "" && present[item.Parent] && item.Parent != item.ID {
+			children[item.Parent] = append(children[item.Parent], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	visited := make([]bool, len(items))
+
+	var walk func(i, depth int)
+	walk = func(i, depth int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+
+		order = append(order, i)
+		depths = append(depths, depth)
+
+		for _, child := range children[items[i].ID] {
+			walk(child, depth+1)
+		}
+	}
+
+	for _, i := range roots {
+		walk(i, 0)
+	}
+
+	// Items caught in a parent cycle are unreachable from any root; show
+	// them at the top level rather than dropping them.
+	for i := range items {
+		walk(i, 0)
+	}
+
+	return order, depths
+}
@@ -0,0 +1,85 @@
+package todo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+func newTree(t *testing.T) todo.List {
+	t.Helper()
+
+	var list todo.List
+	list.Add("Release v2")  // 1
+	list.Add("Write notes") // 2
+	list.Add("Tag build")   // 3
+	list.Add("Unrelated")   // 4
+	list.Add("Proofread")   // 5
+
+	require.NoError(t, list.SetParent(2, 1))
+	require.NoError(t, list.SetParent(3, 1))
+	require.NoError(t, list.SetParent(5, 2))
+
+	return list
+}
+
+func TestSetParentRejectsCycles(t *testing.T) {
+	list := newTree(t)
+
+	assert.Error(t, list.SetParent(1, 1))
+	assert.Error(t, list.SetParent(1, 5), "a task cannot move under its own subtask")
+	assert.Error(t, list.SetParent(1, 9))
+
+	require.NoError(t, list.SetParent(5, 0))
+	assert.Empty(t, list[4].Parent)
+}
+
+func TestProgress(t *testing.T) {
+	list := newTree(t)
+	require.NoError(t, list.Complete(5))
+
+	done, total := list.Progress(1)
+	assert.Equal(t, 1, done)
+	assert.Equal(t, 3, total)
+
+	assert.Equal(t, []int{2, 5, 3}, list.Descendants(1))
+}
+
+func TestString(t *testing.T) {
+	list := newTree(t)
+	require.NoError(t, list.Complete(3))
+
+	expected := "1. [ ] Release v2 (1/3 done)\n" +
+		"  2. [ ] Write notes (0/1 done)\n" +
+		"    5. [ ] Proofread\n" +
+		"  3. [x] Tag build\n" +
+		"4. [ ] Unrelated\n"
+
+	assert.Equal(t, expected, list.String())
+}
+
+func TestDeleteReparentsSubtasks(t *testing.T) {
+	list := newTree(t)
+	parentID := list[0].ID
+
+	require.NoError(t, list.Delete(2))
+
+	// "Proofread" moves up under "Release v2".
+	assert.Equal(t, parentID, list[3].Parent)
+}
+
+func TestTreeOrderSurvivesCycles(t *testing.T) {
+	items := []todo.Item{
+		{ID: "aaaa", Parent: "bbbb"},
+		{ID: "bbbb", Parent: "aaaa"},
+		{ID: "cccc"},
+	}
+
+	order, depths := todo.TreeOrder(items)
+
+	assert.ElementsMatch(t, []int{0, 1, 2}, order)
+	assert.Len(t, depths, 3)
+}
@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"bufio"
+	"unicode/utf8"
+)
+
+// KeyCode identifies a key that isn't plain text.
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota // a printable character, in Key.Rune
+	KeyUp
+	KeyDown
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyBackspace
+	KeyEscape
+	KeyCtrlC
+	KeyUnknown
+)
+
+// Key is a single key press.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Runes returns a key press for each character of s, for scripting input.
+func Runes(s string) []Key {
+	var keys []Key
+	for _, r := range s {
+		keys = append(keys, Key{Code: KeyRune, Rune: r})
+	}
+	return keys
+}
+
+// readKey decodes one key press from a terminal in raw mode. Escape
+// sequences for special keys arrive in a single read, so a lone ESC byte
+// with nothing buffered after it is the Escape key itself.
+func readKey(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch b {
+	case '\r', '\n':
+		return Key{Code: KeyEnter}, nil
+	case 0x7f, 0x08:
+		return Key{Code: KeyBackspace}, nil
+	case 0x03:
+		return Key{Code: KeyCtrlC}, nil
+	case 0x1b:
+		if r.Buffered() == 0 {
+			return Key{Code: KeyEscape}, nil
+		}
+		return readEscape(r)
+	}
+
+	if b < utf8.RuneSelf {
+		if b < 0x20 {
+			return Key{Code: KeyUnknown}, nil
+		}
+		return Key{Code: KeyRune, Rune: rune(b)}, nil
+	}
+
+	if err := r.UnreadByte(); err != nil {
+		return Key{}, err
+	}
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Code: KeyRune, Rune: ch}, nil
+}
+
+// readEscape decodes the rest of an "ESC [" or "ESC O" sequence.
+func readEscape(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	if b != '[' && b != 'O' {
+		return Key{Code: KeyUnknown}, nil
+	}
+
+	// Read parameters up to the final byte, e.g. "1~" for Home.
+	var seq []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		seq = append(seq, b)
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		return Key{Code: KeyUp}, nil
+	case "B":
+		return Key{Code: KeyDown}, nil
+	case "H", "1~", "7~":
+		return Key{Code: KeyHome}, nil
+	case "F", "4~", "8~":
+		return Key{Code: KeyEnd}, nil
+	}
+	return Key{Code: KeyUnknown}, nil
+}
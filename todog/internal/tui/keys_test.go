@@ -0,0 +1,31 @@
+package tui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a\x1b[A\x1b[B\x1b[1~\r\x7f\x03é\x1b"))
+
+	expected := []Key{
+		{Code: KeyRune, Rune: 'a'},
+		{Code: KeyUp},
+		{Code: KeyDown},
+		{Code: KeyHome},
+		{Code: KeyEnter},
+		{Code: KeyBackspace},
+		{Code: KeyCtrlC},
+		{Code: KeyRune, Rune: 'é'},
+		{Code: KeyEscape},
+	}
+	for _, want := range expected {
+		k, err := readKey(r)
+		require.NoError(t, err)
+		assert.Equal(t, want, k)
+	}
+}
@@ -0,0 +1,358 @@
+// Package tui implements todog's full-screen interactive mode. The Model
+// holds the UI state and renders into a Screen, so the whole interface can
+// be driven and inspected without a real terminal.
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+)
+
+// Store loads and changes the todo list behind the UI. Update applies fn to
+// the current list and saves the result, recording it in the history under
+// action, e.g. "toggle ab12"; nothing is saved if fn fails.
+type Store interface {
+	Load() (todo.List, error)
+	Update(action string, fn func(list *todo.List) error) error
+}
+
+type mode int
+
+const (
+	modeNormal mode = iota
+	modeAdd
+	modeEdit
+	modeFilter
+)
+
+var prompts = map[mode]string{
+	modeAdd:    "Add: ",
+	modeEdit:   "Edit: ",
+	modeFilter: "Filter: ",
+}
+
+const helpLine = "j/k move  space toggle  a add  e edit  d delete  / filter  q quit"
+
+// row is a task shown on screen.
+type row struct {
+	num   int
+	item  todo.Item
+	depth int
+}
+
+// Model is the state of the interactive UI.
+type Model struct {
+	store Store
+	list  todo.List
+	rows  []row
+
+	cursor int // index into rows
+	offset int // first row shown
+
+	mode   mode
+	input  []rune
+	filter string
+	editID string
+	status string
+
+	now func() time.Time
+}
+
+// NewModel loads the list from store and returns a UI showing it.
+func NewModel(store Store) (*Model, error) {
+	m := &Model{store: store, now: time.Now}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reload reads the list again, keeping the cursor on the same task.
+func (m *Model) reload() error {
+	list, err := m.store.Load()
+	if err != nil {
+		return err
+	}
+
+	m.list = list
+	m.refresh()
+	return nil
+}
+
+// refresh rebuilds the visible rows from the list and the filter.
+func (m *Model) refresh() {
+	selected := m.selectedID()
+
+	filter := m.filter
+	if m.mode == modeFilter {
+		filter = string(m.input)
+	}
+
+	var match todo.Matcher
+	if strings.TrimSpace(filter) != "" {
+		match, _ = todo.SubstringMatcher(filter)
+	}
+
+	order, depths := todo.TreeOrder(m.list)
+	m.rows = m.rows[:0]
+	for n, i := range order {
+		item := m.list[i]
+		if match != nil && match(item.Task) == nil {
+			continue
+		}
+		m.rows = append(m.rows, row{num: i + 1, item: item, depth: depths[n]})
+	}
+
+	for i, r := range m.rows {
+		if r.item.ID == selected {
+			m.cursor = i
+		}
+	}
+	m.cursor = max(0, min(m.cursor, len(m.rows)-1))
+}
+
+func (m *Model) selectedID() string {
+	if m.cursor < len(m.rows) {
+		return m.rows[m.cursor].item.ID
+	}
+	return ""
+}
+
+// HandleKey applies a key press and reports whether the UI should exit.
+func (m *Model) HandleKey(k Key) (quit bool) {
+	if k.Code == KeyCtrlC {
+		return true
+	}
+
+	if m.mode != modeNormal {
+		m.handleInput(k)
+		return false
+	}
+
+	m.status = ""
+
+	switch {
+	case k.Code == KeyUp || k.Rune == 'k':
+		m.cursor = max(0, m.cursor-1)
+	case k.Code == KeyDown || k.Rune == 'j':
+		m.cursor = max(0, min(m.cursor+1, len(m.rows)-1))
+	case k.Code == KeyHome || k.Rune == 'g':
+		m.cursor = 0
+	case k.Code == KeyEnd || k.Rune == 'G':
+		m.cursor = max(0, len(m.rows)-1)
+	case k.Rune == ' ':
+		m.toggle()
+	case k.Rune == 'a':
+		m.startInput(modeAdd, "")
+	case k.Rune == 'e':
+		if m.cursor < len(m.rows) {
+			m.editID = m.selectedID()
+			m.startInput(modeEdit, m.rows[m.cursor].item.Task)
+		}
+	case k.Rune == 'd':
+		m.delete()
+	case k.Rune == '/':
+		m.startInput(modeFilter, m.filter)
+	case k.Code == KeyEscape:
+		m.filter = ""
+		m.refresh()
+	case k.Rune == 'q':
+		return true
+	}
+
+	return false
+}
+
+func (m *Model) startInput(md mode, text string) {
+	m.mode = md
+	m.input = []rune(text)
+}
+
+// handleInput edits the prompt line in the add, edit, and filter modes.
+func (m *Model) handleInput(k Key) {
+	switch k.Code {
+	case KeyRune:
+		m.input = append(m.input, k.Rune)
+	case KeyBackspace:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case KeyEscape:
+		m.mode = modeNormal
+	case KeyEnter:
+		text := strings.TrimSpace(string(m.input))
+		switch m.mode {
+		case modeAdd:
+			if text != "" {
+				m.add(text)
+			}
+		case modeEdit:
+			if text != "" {
+				m.edit(text)
+			}
+		case modeFilter:
+			m.filter = text
+		}
+		m.mode = modeNormal
+	}
+
+	m.refresh()
+}
+
+// update applies fn to the stored list as action and shows the result or the
+// error. It reports whether the change was saved.
+func (m *Model) update(action string, fn func(list *todo.List) error, format string, args ...any) bool {
+	err := m.store.Update(action, fn)
+	if err != nil {
+		m.status = "Error: " + err.Error()
+	} else {
+		m.status = fmt.Sprintf(format, args...)
+	}
+
+	if err := m.reload(); err != nil {
+		m.status = "Error: " + err.Error()
+	}
+	return err == nil
+}
+
+func (m *Model) add(task string) {
+	var added todo.Item
+	ok := m.update("add "+task, func(list *todo.List) error {
+		added = list.Add(task)
+		return nil
+	}, "Added task: %q", task)
+
+	// Move to the new task, clearing a filter that would hide it.
+	if ok {
+		m.filter = ""
+		m.refresh()
+		for i, r := range m.rows {
+			if r.item.ID == added.ID {
+				m.cursor = i
+			}
+		}
+	}
+}
+
+func (m *Model) edit(task string) {
+	id := m.editID
+	m.update("edit "+id+" "+task, func(list *todo.List) error {
+		n, err := list.Resolve(id)
+		if err != nil {
+			return err
+		}
+		return list.Edit(n, task)
+	}, "Updated task [%s].", id)
+}
+
+func (m *Model) toggle() {
+	if m.cursor >= len(m.rows) {
+		return
+	}
+
+	id := m.selectedID()
+	m.update("toggle "+id, func(list *todo.List) error {
+		n, err := list.Resolve(id)
+		if err != nil {
+			return err
+		}
+		return list.Toggle(n)
+	}, "Toggled task [%s].", id)
+}
+
+func (m *Model) delete() {
+	if m.cursor >= len(m.rows) {
+		return
+	}
+
+	id := m.selectedID()
+	m.update("delete "+id, func(list *todo.List) error {
+		n, err := list.Resolve(id)
+		if err != nil {
+			return err
+		}
+		return list.Delete(n)
+	}, "Deleted task [%s]. Run todog undo to restore it.", id)
+}
+
+// Render draws the UI: a header, the visible tasks, a status line, and
+// either the key help or the input prompt.
+func (m *Model) Render(s *Screen) {
+	_, height := s.Size()
+	listHeight := max(1, height-3)
+
+	open := 0
+	for _, item := range m.list {
+		if !item.Done {
+			open++
+		}
+	}
+	noun := "tasks"
+	if len(m.list) == 1 {
+		noun = "task"
+	}
+	header := fmt.Sprintf("todog: %d %s, %d open", len(m.list), noun, open)
+	if m.filter != "" {
+		header += fmt.Sprintf("  (filter: %s)", m.filter)
+	}
+	s.SetLine(0, header)
+
+	// Scroll just enough to keep the cursor visible.
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+listHeight {
+		m.offset = m.cursor - listHeight + 1
+	}
+
+	if len(m.rows) == 0 {
+		s.SetLine(1, "  No tasks. Press a to add one.")
+	}
+	for y := 0; y < listHeight && m.offset+y < len(m.rows); y++ {
+		i := m.offset + y
+		s.SetLine(1+y, m.formatRow(m.rows[i], i == m.cursor))
+	}
+
+	s.SetLine(height-2, m.status)
+
+	if prompt, ok := prompts[m.mode]; ok {
+		s.SetLine(height-1, prompt+string(m.input)+"_")
+	} else {
+		s.SetLine(height-1, helpLine)
+	}
+}
+
+func (m *Model) formatRow(r row, selected bool) string {
+	var b strings.Builder
+
+	if selected {
+		b.WriteString("> ")
+	} else {
+		b.WriteString("  ")
+	}
+	b.WriteString(strings.Repeat("  ", r.depth))
+
+	status := "[ ]"
+	if r.item.Done {
+		status = "[x]"
+	}
+	fmt.Fprintf(&b, "%d. %s ", r.num, status)
+
+	if r.item.Priority != "" {
+		fmt.Fprintf(&b, "(%s) ", r.item.Priority)
+	}
+	b.WriteString(r.item.Task)
+
+	if r.item.HasDue() {
+		due := r.item.Due.Format(todo.DateLayout)
+		if r.item.IsOverdue(m.now()) {
+			due += ", OVERDUE"
+		}
+		fmt.Fprintf(&b, " (due %s)", due)
+	}
+
+	return b.String()
+}
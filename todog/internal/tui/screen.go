@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Screen is an in-memory grid of text lines that the UI renders into. A
+// terminal copies it to the display; tests read it back directly.
+type Screen struct {
+	width, height int
+	lines         []string
+}
+
+// NewScreen returns a blank screen of the given size.
+func NewScreen(width, height int) *Screen {
+	return &Screen{width: width, height: height, lines: make([]string, height)}
+}
+
+// Size returns the screen's width and height in cells.
+func (s *Screen) Size() (width, height int) {
+	return s.width, s.height
+}
+
+// SetLine replaces line y, cutting text that doesn't fit. Lines outside the
+// screen are ignored.
+func (s *Screen) SetLine(y int, text string) {
+	if y < 0 || y >= s.height {
+		return
+	}
+
+	if utf8.RuneCountInString(text) > s.width {
+		text = string([]rune(text)[:s.width])
+	}
+	s.lines[y] = text
+}
+
+// Line returns line y.
+func (s *Screen) Line(y int) string {
+	if y < 0 || y >= s.height {
+		return ""
+	}
+	return s.lines[y]
+}
+
+// String returns every line, separated by newlines.
+func (s *Screen) String() string {
+	return strings.Join(s.lines, "\n")
+}
@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Terminal is where the UI is displayed and reads its input.
+type Terminal interface {
+	Size() (width, height int)
+	ReadKey() (Key, error)
+	Draw(s *Screen) error
+}
+
+// Run shows m on t until the user quits or input ends.
+func Run(t Terminal, m *Model) error {
+	for {
+		width, height := t.Size()
+		s := NewScreen(width, height)
+		m.Render(s)
+		if err := t.Draw(s); err != nil {
+			return err
+		}
+
+		k, err := t.ReadKey()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if m.HandleKey(k) {
+			return nil
+		}
+	}
+}
+
+// Headless is a Terminal with scripted input that keeps every screen it is
+// asked to draw, for exercising the UI without a TTY.
+type Headless struct {
+	Width, Height int
+	Keys          []Key
+	Screens       []*Screen
+}
+
+// NewHeadless returns a headless terminal of the given size that replays
+// keys and then reports the end of input.
+func NewHeadless(width, height int, keys ...Key) *Headless {
+	return &Headless{Width: width, Height: height, Keys: keys}
+}
+
+func (h *Headless) Size() (int, int) { return h.Width, h.Height }
+
+func (h *Headless) ReadKey() (Key, error) {
+	if len(h.Keys) == 0 {
+		return Key{}, io.EOF
+	}
+	k := h.Keys[0]
+	h.Keys = h.Keys[1:]
+	return k, nil
+}
+
+func (h *Headless) Draw(s *Screen) error {
+	h.Screens = append(h.Screens, s)
+	return nil
+}
+
+// Last returns the most recently drawn screen.
+func (h *Headless) Last() *Screen {
+	if len(h.Screens) == 0 {
+		return nil
+	}
+	return h.Screens[len(h.Screens)-1]
+}
+
+// TTY is a Terminal backed by a real terminal in raw mode, using the
+// alternate screen so the shell's contents come back on exit.
+type TTY struct {
+	in      *os.File
+	out     io.Writer
+	r       *bufio.Reader
+	restore func() error
+}
+
+// OpenTTY switches in to raw mode and takes over the screen. Call Close to
+// hand the terminal back.
+func OpenTTY(in *os.File, out io.Writer) (*TTY, error) {
+	restore, err := makeRaw(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// Alternate screen, hidden cursor.
+	if _, err := io.WriteString(out, "\x1b[?1049h\x1b[?25l"); err != nil {
+		restore()
+		return nil, err
+	}
+
+	return &TTY{in: in, out: out, r: bufio.NewReader(in), restore: restore}, nil
+}
+
+// Close restores the terminal to the state OpenTTY found it in.
+func (t *TTY) Close() error {
+	_, err := io.WriteString(t.out, "\x1b[?25h\x1b[?1049l")
+	if rerr := t.restore(); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+func (t *TTY) Size() (int, int) {
+	if width, height, ok := terminalSize(t.in); ok {
+		return width, height
+	}
+	return 80, 24
+}
+
+func (t *TTY) ReadKey() (Key, error) {
+	return readKey(t.r)
+}
+
+func (t *TTY) Draw(s *Screen) error {
+	var b strings.Builder
+
+	_, height := s.Size()
+	b.WriteString("\x1b[H")
+	for y := 0; y < height; y++ {
+		b.WriteString(s.Line(y))
+		b.WriteString("\x1b[K") // clear what's left of the line
+		if y < height-1 {
+			b.WriteString("\r\n")
+		}
+	}
+
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
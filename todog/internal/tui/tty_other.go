@@ -0,0 +1,16 @@
+//go:build !unix
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+func makeRaw(*os.File) (func() error, error) {
+	return nil, errors.New("the interactive UI needs a Unix terminal")
+}
+
+func terminalSize(*os.File) (int, int, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// makeRaw puts the terminal into raw mode with stty, which every Unix
+// system has, and returns a function that restores the previous settings.
+func makeRaw(f *os.File) (restore func() error, err error) {
+	state, err := stty(f, "-g")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := stty(f, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		_, err := stty(f, strings.TrimSpace(state))
+		return err
+	}, nil
+}
+
+func terminalSize(f *os.File) (width, height int, ok bool) {
+	out, err := stty(f, "size")
+	if err != nil {
+		return 0, 0, false
+	}
+
+	if _, err := fmt.Sscan(out, &height, &width); err != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,172 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mnishiguchi/command-line-go/todog/internal/todo"
+	"github.com/mnishiguchi/command-line-go/todog/internal/tui"
+)
+
+// memStore keeps the list in memory and records the action of each save.
+type memStore struct {
+	list    todo.List
+	actions []string
+}
+
+func (s *memStore) Load() (todo.List, error) {
+	return append(todo.List(nil), s.list...), nil
+}
+
+func (s *memStore) Update(action string, fn func(list *todo.List) error) error {
+	list := append(todo.List(nil), s.list...)
+	if err := fn(&list); err != nil {
+		return err
+	}
+	s.list = list
+	s.actions = append(s.actions, action)
+	return nil
+}
+
+func newStore(tasks ...string) *memStore {
+	s := &memStore{}
+	for _, task := range tasks {
+		s.list.Add(task)
+	}
+	return s
+}
+
+func run(t *testing.T, store *memStore, keys ...tui.Key) *tui.Headless {
+	t.Helper()
+
+	m, err := tui.NewModel(store)
+	require.NoError(t, err)
+
+	term := tui.NewHeadless(60, 8, keys...)
+	require.NoError(t, tui.Run(term, m))
+	return term
+}
+
+var (
+	down      = tui.Key{Code: tui.KeyDown}
+	enter     = tui.Key{Code: tui.KeyEnter}
+	escape    = tui.Key{Code: tui.KeyEscape}
+	backspace = tui.Key{Code: tui.KeyBackspace}
+)
+
+func keys(groups ...any) []tui.Key {
+	var result []tui.Key
+	for _, g := range groups {
+		switch g := g.(type) {
+		case string:
+			result = append(result, tui.Runes(g)...)
+		case tui.Key:
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+func TestRenderList(t *testing.T) {
+	store := newStore("Write report", "Call Bob")
+	term := run(t, store)
+
+	assert.Equal(t, strings.Join([]string{
+		"todog: 2 tasks, 2 open",
+		"> 1. [ ] Write report",
+		"  2. [ ] Call Bob",
+		"", "", "", "",
+		"j/k move  space toggle  a add  e edit  d delete  / filter  q",
+	}, "\n"), term.Last().String())
+}
+
+func TestNavigateAndToggle(t *testing.T) {
+	store := newStore("Write report", "Call Bob")
+	term := run(t, store, keys("j", " ")...)
+
+	assert.True(t, store.list[1].Done)
+	assert.False(t, store.list[0].Done)
+	assert.Equal(t, "> 2. [x] Call Bob", term.Last().Line(2))
+	assert.Contains(t, term.Last().Line(6), "Toggled task")
+	assert.Equal(t, []string{"toggle " + store.list[1].ID}, store.actions)
+
+	// Moving past the ends stays on the first or last task.
+	term = run(t, store, keys("k", "k", down, down, down)...)
+	assert.Equal(t, "> 2. [x] Call Bob", term.Last().Line(2))
+}
+
+func TestAddEditDelete(t *testing.T) {
+	store := newStore("Write report")
+
+	run(t, store, keys("a", "Call Bbo", backspace, backspace, "ob", enter)...)
+	require.Len(t, store.list, 2)
+	assert.Equal(t, "Call Bob", store.list[1].Task)
+	first, second := store.list[0].ID, store.list[1].ID
+
+	term := run(t, store, keys(down, "e", backspace, backspace, backspace, "Alice", enter)...)
+	assert.Equal(t, "Call Alice", store.list[1].Task)
+	assert.Equal(t, "> 2. [ ] Call Alice", term.Last().Line(2))
+
+	run(t, store, keys("d")...)
+	require.Len(t, store.list, 1)
+	assert.Equal(t, "Call Alice", store.list[0].Task)
+
+	assert.Equal(t, []string{
+		"add Call Bob",
+		"edit " + second + " Call Alice",
+		"delete " + first,
+	}, store.actions)
+
+	// Escape abandons input without saving.
+	saves := len(store.actions)
+	run(t, store, keys("a", "Never mind", escape, "e", "!!", escape)...)
+	assert.Equal(t, saves, len(store.actions))
+}
+
+func TestFilter(t *testing.T) {
+	store := newStore("Write report", "Call Bob", "Review report draft")
+
+	term := run(t, store, keys("/", "rep")...)
+	assert.Equal(t, "Filter: rep_", term.Last().Line(7))
+	assert.Equal(t, "> 1. [ ] Write report", term.Last().Line(1))
+	assert.Equal(t, "  3. [ ] Review report draft", term.Last().Line(2))
+	assert.Equal(t, "", term.Last().Line(3))
+
+	term = run(t, store, keys("/", "draft", enter, " ")...)
+	assert.Contains(t, term.Last().Line(0), "(filter: draft)")
+	assert.True(t, store.list[2].Done, "toggle acts on the filtered task")
+
+	term = run(t, store, keys("/", "draft", enter, escape)...)
+	assert.Equal(t, "todog: 3 tasks, 2 open", term.Last().Line(0))
+}
+
+func TestScrolling(t *testing.T) {
+	store := newStore("one", "two", "three", "four", "five", "six", "seven")
+
+	// Eight lines leave room for five tasks.
+	term := run(t, store, keys("G")...)
+	assert.Equal(t, "  3. [ ] three", term.Last().Line(1))
+	assert.Equal(t, "> 7. [ ] seven", term.Last().Line(5))
+
+	term = run(t, store, keys("G", "g")...)
+	assert.Equal(t, "> 1. [ ] one", term.Last().Line(1))
+}
+
+func TestQuit(t *testing.T) {
+	store := newStore("one")
+	term := run(t, store, keys("q", " ")...)
+
+	assert.False(t, store.list[0].Done, "keys after q are ignored")
+	assert.Len(t, term.Keys, 1)
+}
+
+func TestSubtasksIndented(t *testing.T) {
+	store := newStore("Release", "Write notes")
+	require.NoError(t, store.list.SetParent(2, 1))
+
+	term := run(t, store)
+	assert.Equal(t, "    2. [ ] Write notes", term.Last().Line(2))
+}